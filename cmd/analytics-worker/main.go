@@ -10,6 +10,7 @@ import (
 
 	"github.com/Kalmera74/Shorty/internal/db"
 	"github.com/Kalmera74/Shorty/internal/features/analytics"
+	"github.com/Kalmera74/Shorty/pkg/geoip"
 	"github.com/Kalmera74/Shorty/pkg/messaging"
 
 	"github.com/rs/zerolog"
@@ -31,8 +32,20 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to connect to the database")
 	}
 
+	var opts []analytics.AnalyticsServiceOption
+	if path := os.Getenv("GEOIP_DB_PATH"); path != "" {
+		locator, err := geoip.Open(path)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open GeoIP database")
+		}
+		defer locator.Close()
+		opts = append(opts, analytics.WithGeoLocator(locator))
+	} else {
+		log.Info().Msg("GEOIP_DB_PATH not set, clicks are stored without a country")
+	}
+
 	analyticsStore := analytics.NewAnalyticsRepository(dbConn)
-	analyticsService := analytics.NewAnalyticService(analyticsStore)
+	analyticsService := analytics.NewAnalyticService(analyticsStore, opts...)
 
 	var mq messaging.IMessaging
 	mq, err = messaging.NewRabbitMQConnection()
@@ -129,6 +142,7 @@ func processMessages(ctx context.Context, msgs <-chan messaging.IMessage, servic
 				IpAddress: click.Ip,
 				UserAgent: click.UserAgent,
 				CreatedAt: click.TimeStamp,
+				VariantID: click.VariantID,
 			}
 
 			if _, err := service.Create(ctx, record); err != nil {
@@ -1,16 +1,23 @@
 package main
 
 import (
+	"context"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Kalmera74/Shorty/internal/db"
 	"github.com/Kalmera74/Shorty/internal/features/analytics"
+	"github.com/Kalmera74/Shorty/internal/features/domain"
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
 	"github.com/Kalmera74/Shorty/internal/features/user"
 	"github.com/Kalmera74/Shorty/pkg/auth"
 	"github.com/Kalmera74/Shorty/pkg/cache"
+	"github.com/Kalmera74/Shorty/pkg/geoip"
 	"github.com/Kalmera74/Shorty/pkg/messaging"
+	"github.com/Kalmera74/Shorty/pkg/wordlist"
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
@@ -73,20 +80,106 @@ func main() {
 	if err := mq.DeclareQueue(clickQue); err != nil {
 		log.Fatal().Err(err).Msg("Failed to declare RabbitMQ queue")
 	}
+
+	metadataQueue := os.Getenv("METADATA_QUEUE")
+	if metadataQueue == "" {
+		metadataQueue = shortener.DefaultMetadataQueue
+	}
+	if err := mq.DeclareQueue(metadataQueue); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare RabbitMQ queue")
+	}
 	defer mq.Close()
 
+	var locator geoip.ILocator
+	if path := os.Getenv("GEOIP_DB_PATH"); path != "" {
+		mmdb, err := geoip.Open(path)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open GeoIP database")
+		}
+		defer mmdb.Close()
+		locator = mmdb
+	} else {
+		log.Info().Msg("GEOIP_DB_PATH not set, country redirect rules are disabled")
+	}
+
 	userStore := user.NewUserRepository(dbConn)
-	userService := user.NewUserService(userStore)
+	userService := user.NewUserService(userStore, user.WithShortCache(cacher))
 	userHandler := user.NewUserHandler(userService)
 	user.RegisterRoutes(app, userHandler)
 
+	domainStore := domain.NewDomainRepository(dbConn)
+	domainService := domain.NewDomainService(domainStore, cacher, net.DefaultResolver)
+	domainHandler := domain.NewDomainHandler(domainService)
+	domain.RegisterRoutes(app, domainHandler)
+
+	codeGenerator, err := shortener.NewCodeGeneratorFromEnv(cacher)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure short code generator")
+	}
+	codeRetries, _ := strconv.Atoi(os.Getenv("SHORT_CODE_MAX_RETRIES"))
+	bulkMaxItems, _ := strconv.Atoi(os.Getenv("BULK_MAX_ITEMS"))
+
+	urlPolicy := shortener.DefaultURLPolicy()
+	urlPolicy.Resolver = net.DefaultResolver
+	urlPolicy.AllowPrivate, _ = strconv.ParseBool(os.Getenv("URL_ALLOW_PRIVATE"))
+	if schemes := os.Getenv("URL_ALLOWED_SCHEMES"); schemes != "" {
+		urlPolicy.AllowedSchemes = strings.Split(schemes, ",")
+	}
+	if path := os.Getenv("URL_BLOCKLIST_PATH"); path != "" {
+		blocklist, err := wordlist.Load(path)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load URL blocklist")
+		}
+		urlPolicy.Blocklist = blocklist
+	}
+
+	slugPolicy := shortener.DefaultSlugPolicy()
+	slugPolicy.Roles = userService
+	slugPolicy.Reserved = loadWordlist("SLUG_RESERVED_PATH")
+	slugPolicy.Profanity = loadWordlist("SLUG_PROFANITY_PATH")
+	slugPolicy.Trademarks = loadWordlist("SLUG_TRADEMARK_PATH")
+	if n, err := strconv.Atoi(os.Getenv("SLUG_MIN_LENGTH")); err == nil && n > 0 {
+		slugPolicy.MinLength = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("SLUG_ADMIN_MIN_LENGTH")); err == nil && n > 0 {
+		slugPolicy.AdminMinLength = n
+	}
+	if cooldown, err := time.ParseDuration(os.Getenv("SLUG_COOLDOWN")); err == nil && cooldown >= 0 {
+		slugPolicy.Cooldown = cooldown
+	}
+
 	shortStore := shortener.NewShortRepository(dbConn)
-	shortService := shortener.NewShortService(shortStore, cacher)
+	shortService := shortener.NewShortService(shortStore, cacher,
+		shortener.WithCodeGenerator(codeGenerator),
+		shortener.WithCodeRetries(codeRetries),
+		shortener.WithBulkMaxItems(bulkMaxItems),
+		shortener.WithDomainLookup(domainService),
+		shortener.WithComingSoonUrl(os.Getenv("COMING_SOON_URL")),
+		shortener.WithGeoLocator(locator),
+		shortener.WithURLPolicy(urlPolicy),
+		shortener.WithSlugPolicy(slugPolicy),
+		shortener.WithCreatorLookup(userService),
+		shortener.WithMetadataJobs(mq, metadataQueue),
+	)
+
+	if urlPolicy.Blocklist != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go watchBlocklist(ctx, urlPolicy.Blocklist, shortService)
+	}
+
+	purgeCtx, cancelPurge := context.WithCancel(context.Background())
+	defer cancelPurge()
+	go purgeTrash(purgeCtx, shortService, userService)
+
 	shortHandler := shortener.NewShortHandler(shortService, mq)
 	shortener.RegisterRoutes(app, shortHandler)
 
 	analyticsStore := analytics.NewAnalyticsRepository(dbConn)
-	analyticsService := analytics.NewAnalyticService(analyticsStore)
+	analyticsService := analytics.NewAnalyticService(analyticsStore,
+		analytics.WithGeoLocator(locator),
+		analytics.WithCampaignLookup(shortService),
+	)
 	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService)
 	analytics.RegisterRoutes(app, analyticsHandler)
 
@@ -97,3 +190,76 @@ func main() {
 
 	log.Fatal().Err(app.Listen(":" + port)).Msg("Shorty app encounter a problem, quitting")
 }
+
+// loadWordlist loads the word list at the path held by env, or returns nil
+// when env is not set.
+func loadWordlist(env string) *wordlist.List {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	list, err := wordlist.Load(path)
+	if err != nil {
+		log.Fatal().Err(err).Str("env", env).Msg("Failed to load word list")
+	}
+	return list
+}
+
+// purgeTrash deletes shorts and users that have been in the trash for longer
+// than TRASH_RETENTION for good, at startup and every TRASH_PURGE_INTERVAL.
+// Shorts go first so the shorts a user deleted themselves are not waiting on
+// the user.
+func purgeTrash(ctx context.Context, shorts shortener.IShortService, users user.IUserService) {
+	retention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil || retention <= 0 {
+		retention = shortener.DefaultTrashRetention
+	}
+	interval, err := time.ParseDuration(os.Getenv("TRASH_PURGE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if purged, err := shorts.PurgeTrash(ctx, retention); err != nil {
+			log.Error().Err(err).Msg("Failed to purge trashed shorts")
+		} else if purged > 0 {
+			log.Info().Int("purged", purged).Msg("Purged trashed shorts")
+		}
+		if purged, err := users.PurgeTrash(ctx, retention); err != nil {
+			log.Error().Err(err).Msg("Failed to purge trashed users")
+		} else if purged > 0 {
+			log.Info().Int("purged", purged).Msg("Purged trashed users")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchBlocklist applies the URL blocklist to the existing shorts at startup
+// and again whenever the file changes.
+func watchBlocklist(ctx context.Context, blocklist *wordlist.List, service shortener.IShortService) {
+	enforce := func() {
+		changed, err := service.EnforceBlocklist(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to apply URL blocklist")
+			return
+		}
+		log.Info().Int("changed", changed).Msg("Applied URL blocklist to existing shorts")
+	}
+
+	interval, err := time.ParseDuration(os.Getenv("URL_BLOCKLIST_RELOAD_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = time.Minute
+	}
+
+	enforce()
+	blocklist.Watch(ctx, interval, enforce, func(err error) {
+		log.Warn().Err(err).Msg("Failed to reload URL blocklist, keeping the previous one")
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/db"
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/pkg/cache"
+	"github.com/Kalmera74/Shorty/pkg/linkcheck"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultCheckInterval = time.Hour
+
+func main() {
+
+	zerolog.TimeFieldFormat = time.RFC3339
+	logger := zerolog.New(os.Stderr).With().Timestamp().Str("worker", "health").Logger()
+	log.Logger = logger
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	dbConn, err := db.ConnectDB()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to the database")
+	}
+
+	checker := linkcheck.NewChecker()
+	if timeout, err := time.ParseDuration(os.Getenv("HEALTH_CHECK_TIMEOUT")); err == nil && timeout > 0 {
+		checker.Timeout = timeout
+	}
+	checker.AllowPrivate, _ = strconv.ParseBool(os.Getenv("URL_ALLOW_PRIVATE"))
+
+	var config shortener.HealthConfig
+	config.Concurrency, _ = strconv.Atoi(os.Getenv("HEALTH_CONCURRENCY"))
+	config.HostInterval, _ = time.ParseDuration(os.Getenv("HEALTH_HOST_INTERVAL"))
+	config.BrokenAfter, _ = strconv.Atoi(os.Getenv("HEALTH_BROKEN_AFTER"))
+
+	interval, err := time.ParseDuration(os.Getenv("HEALTH_CHECK_INTERVAL"))
+	if err != nil || interval <= 0 {
+		interval = defaultCheckInterval
+	}
+
+	shortStore := shortener.NewShortRepository(dbConn)
+	shortService := shortener.NewShortService(shortStore, caching.NewCacher(), shortener.WithLinkChecker(checker, config))
+
+	log.Info().Dur("interval", interval).Msg("Worker started, checking links")
+
+	checkLoop(ctx, interval, shortService)
+}
+
+func setupSignalHandler(cancel context.CancelFunc) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Info().Msg("Shutting down health worker...")
+		cancel()
+	}()
+}
+
+// checkLoop checks every link right away and then once every interval.
+func checkLoop(ctx context.Context, interval time.Duration, service shortener.IShortService) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		report, err := service.CheckHealth(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Link health check failed")
+		} else {
+			log.Info().
+				Int("checked", report.Checked).
+				Int("broken", report.Broken).
+				Dur("took", time.Since(start)).
+				Msg("Checked link health")
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Context cancelled, exiting worker loop")
+			return
+		case <-ticker.C:
+		}
+	}
+}
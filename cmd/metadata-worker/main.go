@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/db"
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/pkg/messaging"
+	"github.com/Kalmera74/Shorty/pkg/metadata"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+
+	zerolog.TimeFieldFormat = time.RFC3339
+	logger := zerolog.New(os.Stderr).With().Timestamp().Str("worker", "metadata").Logger()
+	log.Logger = logger
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	setupSignalHandler(cancel)
+
+	dbConn, err := db.ConnectDB()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to the database")
+	}
+
+	fetcher := metadata.NewFetcher()
+	if timeout, err := time.ParseDuration(os.Getenv("METADATA_FETCH_TIMEOUT")); err == nil && timeout > 0 {
+		fetcher.Timeout = timeout
+	}
+	if maxBytes, err := strconv.ParseInt(os.Getenv("METADATA_MAX_BYTES"), 10, 64); err == nil && maxBytes > 0 {
+		fetcher.MaxBytes = maxBytes
+	}
+
+	shortStore := shortener.NewShortRepository(dbConn)
+	shortService := shortener.NewShortService(shortStore, nil, shortener.WithMetadataFetcher(fetcher))
+
+	var mq messaging.IMessaging
+	mq, err = messaging.NewRabbitMQConnection()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to RabbitMQ")
+	}
+	defer mq.Close()
+
+	metadataQueue := os.Getenv("METADATA_QUEUE")
+	if metadataQueue == "" {
+		metadataQueue = shortener.DefaultMetadataQueue
+	}
+
+	if err := mq.DeclareQueue(metadataQueue); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare RabbitMQ queue")
+	}
+
+	log.Info().Str("queue", metadataQueue).Msg("Worker started, waiting for messages")
+
+	consumeLoop(ctx, mq, metadataQueue, shortService)
+}
+
+func setupSignalHandler(cancel context.CancelFunc) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Info().Msg("Shutting down metadata worker...")
+		cancel()
+	}()
+}
+
+func consumeLoop(ctx context.Context, mq messaging.IMessaging, queue string, service shortener.IShortService) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Context cancelled, exiting worker loop")
+			return
+		default:
+		}
+
+		msgs, err := mq.Consume(queue, "metadata-worker", false)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to start consumer, reconnecting RabbitMQ...")
+
+			time.Sleep(5 * time.Second)
+			newMq, err := messaging.NewRabbitMQConnection()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to reconnect RabbitMQ, retrying...")
+				continue
+			}
+			mq.Close()
+			mq = newMq
+
+			if err := mq.DeclareQueue(queue); err != nil {
+				log.Error().Err(err).Msg("Failed to declare queue after reconnect")
+				continue
+			}
+			continue
+		}
+
+		processMessages(ctx, msgs, service)
+
+		log.Warn().Msg("RabbitMQ consumer channel closed, reconnecting...")
+		time.Sleep(1 * time.Second)
+	}
+}
+
+func processMessages(ctx context.Context, msgs <-chan messaging.IMessage, service shortener.IShortService) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Context cancelled, stopping message processing")
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				log.Warn().Msg("Message channel closed")
+				return
+			}
+
+			var job shortener.MetadataJob
+			if err := json.Unmarshal(msg.Body(), &job); err != nil {
+				log.Warn().Err(err).Msg("Failed to unmarshal metadata job")
+				_ = msg.Ack()
+				continue
+			}
+
+			log.Info().
+				Uint("short_id", uint(job.ShortID)).
+				Str("url", job.Url).
+				Msg("Fetching metadata")
+
+			err := service.RefreshMetadata(ctx, job)
+			switch {
+			case err == nil:
+				_ = msg.Ack()
+			// The page or the short is gone; trying again will not help.
+			case errors.Is(err, shortener.ErrMetadataFetchFail), errors.Is(err, shortener.ErrShortNotFound):
+				log.Warn().
+					Err(err).
+					Uint("short_id", uint(job.ShortID)).
+					Msg("Could not fetch metadata")
+				_ = msg.Ack()
+			default:
+				log.Error().
+					Err(err).
+					Uint("short_id", uint(job.ShortID)).
+					Msg("Failed to save metadata to DB")
+			}
+		}
+	}
+}
@@ -3,8 +3,10 @@ package db
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/Kalmera74/Shorty/internal/features/analytics"
+	"github.com/Kalmera74/Shorty/internal/features/domain"
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
 	"github.com/Kalmera74/Shorty/internal/features/user"
 	"gorm.io/driver/postgres"
@@ -23,7 +25,7 @@ func ConnectDB() (*gorm.DB, error) {
 		host, user, password, dbname, port,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +36,16 @@ func ConnectDB() (*gorm.DB, error) {
 func AutoMigrate(dbConn *gorm.DB) error {
 	models := []interface{}{
 		&user.UserModel{},
+		&domain.DomainModel{},
+		&shortener.FolderModel{},
+		&shortener.CampaignModel{},
+		&shortener.TagModel{},
 		&shortener.ShortModel{},
+		&shortener.ReleasedSlugModel{},
+		&shortener.DestinationModel{},
+		&shortener.RedirectRuleModel{},
+		&shortener.VariantModel{},
+		&shortener.ShortHistoryModel{},
 		&analytics.ClickModel{},
 	}
 
@@ -43,5 +54,30 @@ func AutoMigrate(dbConn *gorm.DB) error {
 			return fmt.Errorf("failed to auto-migrate model: %v", err)
 		}
 	}
+	return clearZeroDeletedAt(dbConn)
+}
+
+// zeroTimeCutoff is later than the zero time in any time zone and earlier
+// than any real deletion.
+var zeroTimeCutoff = time.Date(2, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// clearZeroDeletedAt turns the zero times that older versions wrote into
+// deleted_at into NULL. Those versions stored a plain time.Time there, so
+// every row they created would otherwise count as deleted, and be purged
+// from the trash right away.
+func clearZeroDeletedAt(dbConn *gorm.DB) error {
+	models := []interface{}{
+		&user.UserModel{},
+		&shortener.ShortModel{},
+		&analytics.ClickModel{},
+	}
+
+	for _, model := range models {
+		if err := dbConn.Unscoped().Model(model).
+			Where("deleted_at < ?", zeroTimeCutoff).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to clear zero deletion times: %v", err)
+		}
+	}
 	return nil
 }
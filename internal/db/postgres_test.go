@@ -0,0 +1,55 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type statement struct {
+	SQL  string
+	Vars []interface{}
+}
+
+// dryRunDB records the statements it is given instead of running them.
+func dryRunDB(t *testing.T) (*gorm.DB, *[]statement) {
+	dbConn, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=shorty_test"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	require.NoError(t, err)
+
+	var statements []statement
+	err = dbConn.Callback().Update().After("gorm:update").Register("test:record", func(tx *gorm.DB) {
+		statements = append(statements, statement{SQL: tx.Statement.SQL.String(), Vars: tx.Statement.Vars})
+	})
+	require.NoError(t, err)
+	return dbConn, &statements
+}
+
+func TestClearZeroDeletedAt(t *testing.T) {
+	dbConn, statements := dryRunDB(t)
+
+	require.NoError(t, clearZeroDeletedAt(dbConn))
+
+	tables := []string{"user_models", "short_models", "click_models"}
+	require.Len(t, *statements, len(tables))
+	for i, table := range tables {
+		stmt := (*statements)[i]
+		assert.Equal(t, `UPDATE "`+table+`" SET "deleted_at"=$1 WHERE deleted_at < $2`, stmt.SQL)
+		require.Len(t, stmt.Vars, 2)
+		assert.Nil(t, stmt.Vars[0])
+		cutoff := stmt.Vars[1].(time.Time)
+
+		// Rows written by older versions hold the zero time, in UTC or
+		// shifted into the server's time zone.
+		assert.True(t, time.Time{}.Before(cutoff))
+		assert.True(t, time.Time{}.Add(14*time.Hour).Before(cutoff))
+		assert.False(t, time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC).Before(cutoff))
+	}
+}
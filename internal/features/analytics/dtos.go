@@ -11,6 +11,9 @@ type ClickEvent struct {
 	Ip        string        `json:"ip"`
 	UserAgent string        `json:"user_agent"`
 	TimeStamp time.Time     `json:"time_stamp"`
+	Country   string        `json:"country,omitempty"`
+	// VariantID is the A/B variant the visitor was sent to, if any.
+	VariantID *types.VariantId `json:"variant_id,omitempty"`
 }
 
 type Analysis struct {
@@ -23,12 +26,15 @@ type Usage struct {
 	ClickTimes time.Time
 	IpAddress  string
 	UserAgents string
+	Country    string
+	VariantID  *types.VariantId
 }
 type PaginatedAnalytics struct {
-	Total     int        `json:"total"`
-	Page      int        `json:"page"`
-	PageSize  int        `json:"page_size"`
-	Analytics []Analysis `json:"analytics"`
+	Total      *int       `json:"total,omitempty"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"page_size"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+	Analytics  []Analysis `json:"analytics"`
 }
 type PaginatedAnalysis struct {
 	Total   int      `json:"total"`
@@ -38,8 +44,31 @@ type PaginatedAnalysis struct {
 }
 
 type PaginatedClicks struct {
-	Total  int          `json:"total"`
-	Page   int          `json:"page"`
-	Limit  int          `json:"limit"`
-	Clicks []ClickEvent `json:"clicks"`
+	Total      *int         `json:"total,omitempty"`
+	Page       int          `json:"page"`
+	Limit      int          `json:"limit"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Clicks     []ClickEvent `json:"clicks"`
+}
+
+// VariantClicks is the number of clicks one A/B variant of a short received.
+type VariantClicks struct {
+	VariantID types.VariantId `json:"variant_id"`
+	Clicks    int             `json:"clicks"`
+}
+
+// ShortClicks is the number of clicks one short of a campaign received.
+type ShortClicks struct {
+	ShortID  types.ShortId `json:"short_id"`
+	ShortUrl string        `json:"short_url"`
+	Clicks   int           `json:"clicks"`
+}
+
+// CampaignClicks sums up the clicks of all shorts in a campaign.
+type CampaignClicks struct {
+	CampaignID types.CampaignId `json:"campaign_id"`
+	Clicks     int              `json:"clicks"`
+	// UniqueVisitors counts distinct IP addresses across the campaign.
+	UniqueVisitors int           `json:"unique_visitors"`
+	Shorts         []ShortClicks `json:"shorts"`
 }
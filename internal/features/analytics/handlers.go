@@ -3,6 +3,9 @@ package analytics
 import (
 	"errors"
 
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/middleware"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,25 +23,24 @@ func NewAnalyticsHandler(service IAnalyticsService) *analyticsHandler {
 // @Description  Returns paginated click analytics grouped by short URLs
 // @Tags         analytics
 // @Produce      json
-// @Param        page     query int false "Page number" default(1)
-// @Param        pageSize query int false "Items per page" default(10)
+// @Param        page     query int    false "Page number, ignored when cursor is set" default(1)
+// @Param        pageSize query int    false "Items per page" default(10)
+// @Param        cursor   query string false "Cursor from the previous page's next_cursor"
+// @Param        count    query bool   false "Include the exact total, on by default only without cursor"
 // @Success      200 {object} PaginatedAnalytics
+// @Failure      400 {object} map[string]string "Invalid cursor"
 // @Failure      404 {object} map[string]string "No clicks found"
 // @Failure      500 {object} map[string]string "Failed to fetch analytics"
 // @Router       /api/v1/analytics [get]
 func (h *analyticsHandler) GetAllAnalytics(c *fiber.Ctx) error {
 	// Parse query params
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("pageSize", 10)
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 10
+	params, page, err := pagination.FromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"error": err.Error()})
 	}
-	offset := (page - 1) * pageSize
 
-	clickModels, total, err := h.service.GetAll(c.Context(), offset, pageSize)
+	clickModels, result, err := h.service.GetAll(c.Context(), params)
 	if err != nil {
 		if errors.Is(err, ErrClicksNotFound) {
 			return c.Status(fiber.StatusNotFound).
@@ -67,6 +69,8 @@ func (h *analyticsHandler) GetAllAnalytics(c *fiber.Ctx) error {
 				ClickTimes: item.CreatedAt,
 				IpAddress:  item.IpAddress,
 				UserAgents: item.UserAgent,
+				Country:    item.Country,
+				VariantID:  item.VariantID,
 			})
 		}
 		analyticsList = append(analyticsList, analysis)
@@ -74,10 +78,11 @@ func (h *analyticsHandler) GetAllAnalytics(c *fiber.Ctx) error {
 
 	// Paginated response
 	response := PaginatedAnalytics{
-		Total:     total,
-		Page:      page,
-		PageSize:  pageSize,
-		Analytics: analyticsList,
+		Total:      result.Total,
+		Page:       page,
+		PageSize:   params.Limit,
+		NextCursor: result.NextCursor,
+		Analytics:  analyticsList,
 	}
 
 	return c.JSON(response)
@@ -108,6 +113,8 @@ func (h *analyticsHandler) CreateClick(c *fiber.Ctx) error {
 		UserAgent: record.UserAgent,
 		CreatedAt: record.TimeStamp,
 		ShortID:   record.ShortID,
+		Country:   record.Country,
+		VariantID: record.VariantID,
 	}
 
 	createdClick, err := h.service.Create(c.Context(), click)
@@ -179,6 +186,8 @@ func (h *analyticsHandler) GetAllAnalyticsByShortUrl(c *fiber.Ctx) error {
 			ClickTimes: item.CreatedAt,
 			IpAddress:  item.IpAddress,
 			UserAgents: item.UserAgent,
+			Country:    item.Country,
+			VariantID:  item.VariantID,
 		})
 	}
 
@@ -192,30 +201,101 @@ func (h *analyticsHandler) GetAllAnalyticsByShortUrl(c *fiber.Ctx) error {
 	return c.JSON(response)
 }
 
+// GetVariantClicks godoc
+// @Summary      Get clicks per A/B variant of a short
+// @Description  Returns how many clicks each A/B variant of the given short received
+// @Tags         analytics
+// @Produce      json
+// @Param        id path int true "Short ID"
+// @Success      200 {array}  VariantClicks
+// @Failure      400 {object} map[string]string "Invalid short ID"
+// @Failure      404 {object} map[string]string "No variant clicks found for this short"
+// @Failure      500 {object} map[string]string "Failed to count variant clicks"
+// @Router       /api/v1/analytics/shorts/{id}/variants [get]
+func (h *analyticsHandler) GetVariantClicks(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"error": "invalid short ID"})
+	}
+
+	counts, err := h.service.GetVariantClicks(c.Context(), types.ShortId(id))
+	if err != nil {
+		if errors.Is(err, ErrClicksNotFound) {
+			return c.Status(fiber.StatusNotFound).
+				JSON(fiber.Map{"error": "no variant clicks found for this short"})
+		}
+		return c.Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{"error": "failed to count variant clicks", "cause": err.Error()})
+	}
+
+	return c.JSON(counts)
+}
+
+// GetCampaignClicks godoc
+// @Summary      Get clicks of one of the authenticated user's campaigns
+// @Description  Returns the total clicks, unique visitors and clicks per short of all shorts in the campaign
+// @Tags         me
+// @Produce      json
+// @Param        id path int true "Campaign ID"
+// @Success      200 {object} CampaignClicks
+// @Failure      400 {object} map[string]string "Invalid campaign ID"
+// @Failure      401 {object} map[string]string
+// @Failure      404 {object} map[string]string "Campaign not found or without shorts"
+// @Failure      500 {object} map[string]string "Failed to count campaign clicks"
+// @Router       /api/v1/me/campaigns/{id}/analytics [get]
+func (h *analyticsHandler) GetCampaignClicks(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).
+			JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"error": "invalid campaign ID"})
+	}
+
+	clicks, err := h.service.GetCampaignClicks(c.Context(), userID, types.CampaignId(id))
+	if err != nil {
+		if errors.Is(err, shortener.ErrCampaignNotFound) {
+			return c.Status(fiber.StatusNotFound).
+				JSON(fiber.Map{"error": "campaign not found"})
+		}
+		if errors.Is(err, ErrClicksNotFound) {
+			return c.Status(fiber.StatusNotFound).
+				JSON(fiber.Map{"error": "no shorts found for this campaign"})
+		}
+		return c.Status(fiber.StatusInternalServerError).
+			JSON(fiber.Map{"error": "failed to count campaign clicks", "cause": err.Error()})
+	}
+
+	return c.JSON(clicks)
+}
 
 // GetAllClicks godoc
 // @Summary      Get paginated click records
 // @Description  Returns all individual click records (not grouped)
 // @Tags         clicks
 // @Produce      json
-// @Param        page     query int false "Page number" default(1)
-// @Param        pageSize query int false "Items per page" default(10)
+// @Param        page     query int    false "Page number, ignored when cursor is set" default(1)
+// @Param        pageSize query int    false "Items per page" default(10)
+// @Param        cursor   query string false "Cursor from the previous page's next_cursor"
+// @Param        count    query bool   false "Include the exact total, on by default only without cursor"
 // @Success      200 {object} PaginatedClicks
+// @Failure      400 {object} map[string]string "Invalid cursor"
 // @Failure      404 {object} map[string]string "No clicks found"
 // @Failure      500 {object} map[string]string "Failed to fetch clicks"
 // @Router       /api/v1/clicks [get]
 func (h *analyticsHandler) GetAllClicks(c *fiber.Ctx) error {
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("pageSize", 10)
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 {
-		pageSize = 10
+	params, page, err := pagination.FromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).
+			JSON(fiber.Map{"error": err.Error()})
 	}
-	offset := (page - 1) * pageSize
 
-	clicks, total, err := h.service.GetAllClicks(c.Context(), offset, pageSize)
+	clicks, result, err := h.service.GetAllClicks(c.Context(), params)
 	if err != nil {
 		if errors.Is(err, ErrClicksNotFound) {
 			return c.Status(fiber.StatusNotFound).
@@ -232,14 +312,17 @@ func (h *analyticsHandler) GetAllClicks(c *fiber.Ctx) error {
 			Ip:        click.IpAddress,
 			UserAgent: click.UserAgent,
 			TimeStamp: click.CreatedAt,
+			Country:   click.Country,
+			VariantID: click.VariantID,
 		})
 	}
 
 	response := PaginatedClicks{
-		Total:  total,
-		Page:   page,
-		Limit:  pageSize,
-		Clicks: clickEvents,
+		Total:      result.Total,
+		Page:       page,
+		Limit:      params.Limit,
+		NextCursor: result.NextCursor,
+		Clicks:     clickEvents,
 	}
 
 	return c.JSON(response)
@@ -279,6 +362,8 @@ func (h *analyticsHandler) GetClickById(c *fiber.Ctx) error {
 		Ip:        click.IpAddress,
 		UserAgent: click.UserAgent,
 		TimeStamp: click.CreatedAt,
+		Country:   click.Country,
+		VariantID: click.VariantID,
 	}
 
 	return c.JSON(clickEvent)
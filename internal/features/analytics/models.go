@@ -5,15 +5,22 @@ import (
 
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
 	"github.com/Kalmera74/Shorty/internal/types"
+	"gorm.io/gorm"
 )
 
 type ClickModel struct {
 	ID        types.ClickId        `json:"id" gorm:"primaryKey"`
 	CreatedAt time.Time            `json:"created_at"`
 	UpdatedAt time.Time            `json:"updated_at"`
-	DeletedAt time.Time            `json:"deleted_at,omitempty" gorm:"index"`
+	DeletedAt gorm.DeletedAt       `json:"deleted_at,omitempty" gorm:"index"`
 	ShortID   types.ShortId        `json:"short_id" validate:"required"`
 	Short     shortener.ShortModel `json:"short,omitempty" gorm:"constraint:OnDelete:CASCADE;"`
 	IpAddress string               `json:"ip_address" validate:"required,ip"`
 	UserAgent string               `json:"user_agent" validate:"required"`
+	// Country is the ISO 3166-1 alpha-2 code of the visitor's country, empty
+	// when it could not be told from the IP address.
+	Country string `json:"country,omitempty" gorm:"size:2;index"`
+	// VariantID is the A/B variant the visitor was sent to, nil when the
+	// short has no variants or another rule chose the destination.
+	VariantID *types.VariantId `json:"variant_id,omitempty" gorm:"index"`
 }
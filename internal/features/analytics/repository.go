@@ -5,15 +5,19 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"gorm.io/gorm"
 )
 
 type IAnalyticsRepository interface {
-	GetAll(ctx context.Context, offset, limit int) ([]ClickModel, int, error)
+	GetAll(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error)
 	GetAllByShortUrl(ctx context.Context, shortUrl string, offset, limit int) ([]ClickModel, int, error)
 	GetByID(ctx context.Context, id types.ClickId) (ClickModel, error)
 	Create(ctx context.Context, click ClickModel) (ClickModel, error)
+	CountByVariant(ctx context.Context, shortID types.ShortId) ([]VariantClicks, error)
+	CountByCampaign(ctx context.Context, campaignID types.CampaignId) ([]ShortClicks, error)
+	CountCampaignVisitors(ctx context.Context, campaignID types.CampaignId) (int, error)
 }
 
 type postgresClickRepository struct {
@@ -31,30 +35,29 @@ func (p *postgresClickRepository) Create(ctx context.Context, click ClickModel)
 	return click, nil
 }
 
-func (p *postgresClickRepository) GetAll(ctx context.Context, offset, limit int) ([]ClickModel, int, error) {
+func (p *postgresClickRepository) GetAll(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error) {
 	var clicks []ClickModel
-	var total int64
 
-	if err := p.db.WithContext(ctx).Model(&ClickModel{}).Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("failed to count clicks: %w", err)
+	total, err := params.Count(p.db.WithContext(ctx).Model(&ClickModel{}))
+	if err != nil {
+		return nil, pagination.Page{}, fmt.Errorf("failed to count clicks: %w", err)
 	}
 
 	result := p.db.
 		WithContext(ctx).
 		Preload("Short").
-		Limit(limit).
-		Offset(offset).
-		Order("id DESC").
+		Scopes(params.Scope("id")).
 		Find(&clicks)
 
 	if result.Error != nil {
-		return nil, 0, fmt.Errorf("failed to fetch clicks: %w", result.Error)
+		return nil, pagination.Page{}, fmt.Errorf("failed to fetch clicks: %w", result.Error)
 	}
 	if len(clicks) == 0 {
-		return nil, 0, ErrClicksNotFound
+		return nil, pagination.Page{}, ErrClicksNotFound
 	}
 
-	return clicks, int(total), nil
+	clicks, next := pagination.Trim(clicks, params.Limit, func(c ClickModel) uint { return uint(c.ID) })
+	return clicks, pagination.Page{NextCursor: next, Total: total}, nil
 }
 
 func (p *postgresClickRepository) GetAllByShortUrl(ctx context.Context, shortUrl string, offset, limit int) ([]ClickModel, int, error) {
@@ -103,3 +106,49 @@ func (p *postgresClickRepository) GetByID(ctx context.Context, id types.ClickId)
 	}
 	return click, nil
 }
+
+func (p *postgresClickRepository) CountByVariant(ctx context.Context, shortID types.ShortId) ([]VariantClicks, error) {
+	var counts []VariantClicks
+	if err := p.db.WithContext(ctx).
+		Model(&ClickModel{}).
+		Select("variant_id, COUNT(*) AS clicks").
+		Where("short_id = ? AND variant_id IS NOT NULL", shortID).
+		Group("variant_id").
+		Order("variant_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count variant clicks for short %d: %w", shortID, err)
+	}
+	return counts, nil
+}
+
+// CountByCampaign returns the clicks of every short in the campaign that is
+// not in the trash, most clicked first. Shorts without clicks are included.
+func (p *postgresClickRepository) CountByCampaign(ctx context.Context, campaignID types.CampaignId) ([]ShortClicks, error) {
+	var counts []ShortClicks
+	if err := p.db.WithContext(ctx).
+		Table("short_models").
+		Select("short_models.id AS short_id, short_models.short_url, COUNT(click_models.id) AS clicks").
+		Joins("LEFT JOIN click_models ON click_models.short_id = short_models.id AND click_models.deleted_at IS NULL").
+		Where("short_models.campaign_id = ? AND short_models.deleted_at IS NULL", campaignID).
+		Group("short_models.id, short_models.short_url").
+		Order("clicks DESC, short_models.id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count clicks for campaign %d: %w", campaignID, err)
+	}
+	return counts, nil
+}
+
+// CountCampaignVisitors returns how many distinct IP addresses clicked any
+// short in the campaign.
+func (p *postgresClickRepository) CountCampaignVisitors(ctx context.Context, campaignID types.CampaignId) (int, error) {
+	var visitors int64
+	if err := p.db.WithContext(ctx).
+		Model(&ClickModel{}).
+		Joins("JOIN short_models ON short_models.id = click_models.short_id AND click_models.deleted_at IS NULL").
+		Where("short_models.campaign_id = ? AND short_models.deleted_at IS NULL", campaignID).
+		Distinct("click_models.ip_address").
+		Count(&visitors).Error; err != nil {
+		return 0, fmt.Errorf("failed to count visitors for campaign %d: %w", campaignID, err)
+	}
+	return int(visitors), nil
+}
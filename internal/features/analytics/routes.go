@@ -1,6 +1,9 @@
 package analytics
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/Kalmera74/Shorty/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
 
 func RegisterRoutes(app *fiber.App, handler *analyticsHandler) {
 	api := app.Group("/api/v1")
@@ -8,6 +11,9 @@ func RegisterRoutes(app *fiber.App, handler *analyticsHandler) {
 	analytics := api.Group("/analytics")
 	analytics.Get("/", handler.GetAllAnalytics)
 	analytics.Get("/shorts/:shortUrl", handler.GetAllAnalyticsByShortUrl)
+	analytics.Get("/shorts/:id/variants", handler.GetVariantClicks)
+
+	api.Get("/me/campaigns/:id/analytics", middleware.Authenticate(), handler.GetCampaignClicks)
 
 	clicks := api.Group("/clicks")
 	clicks.Post("/", handler.CreateClick)
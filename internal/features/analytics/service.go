@@ -4,26 +4,67 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/Kalmera74/Shorty/pkg/geoip"
 )
 
 type IAnalyticsService interface {
-	GetAll(ctx context.Context, offset, limit int) ([]ClickModel, int, error)
+	GetAll(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error)
 	GetAllByShortUrl(ctx context.Context, shortUrl string, offset, limit int) ([]ClickModel, int, error)
 	Create(ctx context.Context, click ClickModel) (ClickModel, error)
-	GetAllClicks(ctx context.Context, offset, limit int) ([]ClickModel, int, error)
+	GetAllClicks(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error)
 	GetByID(ctx context.Context, id types.ClickId) (ClickModel, error)
+	GetVariantClicks(ctx context.Context, shortID types.ShortId) ([]VariantClicks, error)
+	GetCampaignClicks(ctx context.Context, userID types.UserId, campaignID types.CampaignId) (CampaignClicks, error)
+}
+
+// ICampaignLookup finds a campaign of a user, reporting other users'
+// campaigns as missing. It is implemented by the shortener feature.
+type ICampaignLookup interface {
+	GetCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) (shortener.CampaignModel, error)
 }
 
 type analyticsService struct {
 	Repository IAnalyticsRepository
+	Locator    geoip.ILocator
+	Campaigns  ICampaignLookup
 }
 
-func NewAnalyticService(p IAnalyticsRepository) IAnalyticsService {
-	return &analyticsService{p}
+// AnalyticsServiceOption customises the service returned by
+// NewAnalyticService.
+type AnalyticsServiceOption func(*analyticsService)
+
+// WithGeoLocator fills in the country of clicks recorded without one.
+func WithGeoLocator(locator geoip.ILocator) AnalyticsServiceOption {
+	return func(s *analyticsService) {
+		s.Locator = locator
+	}
+}
+
+// WithCampaignLookup lets users read the analytics of their own campaigns.
+// Without it every campaign is reported as missing.
+func WithCampaignLookup(campaigns ICampaignLookup) AnalyticsServiceOption {
+	return func(s *analyticsService) {
+		s.Campaigns = campaigns
+	}
+}
+
+func NewAnalyticService(p IAnalyticsRepository, opts ...AnalyticsServiceOption) IAnalyticsService {
+	s := &analyticsService{Repository: p}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *analyticsService) Create(ctx context.Context, click ClickModel) (ClickModel, error) {
+	if click.Country == "" && s.Locator != nil {
+		// A click is worth keeping even when its address cannot be placed.
+		click.Country, _ = s.Locator.Country(click.IpAddress)
+	}
+
 	createdClick, err := s.Repository.Create(ctx, click)
 	if err != nil {
 		return ClickModel{}, fmt.Errorf("%w: %v", ErrClickCreateFail, err)
@@ -31,15 +72,15 @@ func (s *analyticsService) Create(ctx context.Context, click ClickModel) (ClickM
 	return createdClick, nil
 }
 
-func (s *analyticsService) GetAll(ctx context.Context, offset, limit int) ([]ClickModel, int, error) {
-	clicks, total, err := s.Repository.GetAll(ctx, offset, limit)
+func (s *analyticsService) GetAll(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error) {
+	clicks, page, err := s.Repository.GetAll(ctx, params)
 	if err != nil {
-		return nil, 0, fmt.Errorf("could not retrieve clicks: %w", err)
+		return nil, pagination.Page{}, fmt.Errorf("could not retrieve clicks: %w", err)
 	}
 	if len(clicks) == 0 {
-		return nil, 0, ErrClicksNotFound
+		return nil, pagination.Page{}, ErrClicksNotFound
 	}
-	return clicks, total, nil
+	return clicks, page, nil
 }
 
 func (s *analyticsService) GetAllByShortUrl(ctx context.Context, shortUrl string, offset, limit int) ([]ClickModel, int, error) {
@@ -53,15 +94,15 @@ func (s *analyticsService) GetAllByShortUrl(ctx context.Context, shortUrl string
 	return clicks, total, nil
 }
 
-func (s *analyticsService) GetAllClicks(ctx context.Context, offset, limit int) ([]ClickModel, int, error) {
-	clicks, total, err := s.Repository.GetAll(ctx, offset, limit)
+func (s *analyticsService) GetAllClicks(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error) {
+	clicks, page, err := s.Repository.GetAll(ctx, params)
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Page{}, err
 	}
 	if len(clicks) == 0 {
-		return nil, 0, ErrClicksNotFound
+		return nil, pagination.Page{}, ErrClicksNotFound
 	}
-	return clicks, total, nil
+	return clicks, page, nil
 }
 
 func (s *analyticsService) GetByID(ctx context.Context, id types.ClickId) (ClickModel, error) {
@@ -74,3 +115,46 @@ func (s *analyticsService) GetByID(ctx context.Context, id types.ClickId) (Click
 	}
 	return click, nil
 }
+
+// GetVariantClicks returns how many clicks each A/B variant of the short
+// received, in variant order.
+func (s *analyticsService) GetVariantClicks(ctx context.Context, shortID types.ShortId) ([]VariantClicks, error) {
+	counts, err := s.Repository.CountByVariant(ctx, shortID)
+	if err != nil {
+		return nil, err
+	}
+	if len(counts) == 0 {
+		return nil, fmt.Errorf("%w: no variant clicks found for short %d", ErrClicksNotFound, shortID)
+	}
+	return counts, nil
+}
+
+// GetCampaignClicks aggregates the clicks of all shorts in a campaign of
+// userID.
+func (s *analyticsService) GetCampaignClicks(ctx context.Context, userID types.UserId, campaignID types.CampaignId) (CampaignClicks, error) {
+	if s.Campaigns == nil {
+		return CampaignClicks{}, fmt.Errorf("%w: %d", shortener.ErrCampaignNotFound, campaignID)
+	}
+	if _, err := s.Campaigns.GetCampaign(ctx, userID, campaignID); err != nil {
+		return CampaignClicks{}, err
+	}
+
+	shorts, err := s.Repository.CountByCampaign(ctx, campaignID)
+	if err != nil {
+		return CampaignClicks{}, err
+	}
+	if len(shorts) == 0 {
+		return CampaignClicks{}, fmt.Errorf("%w: no shorts found for campaign %d", ErrClicksNotFound, campaignID)
+	}
+
+	visitors, err := s.Repository.CountCampaignVisitors(ctx, campaignID)
+	if err != nil {
+		return CampaignClicks{}, err
+	}
+
+	result := CampaignClicks{CampaignID: campaignID, UniqueVisitors: visitors, Shorts: shorts}
+	for _, short := range shorts {
+		result.Clicks += short.Clicks
+	}
+	return result, nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,13 +21,13 @@ func (m *mockAnalyticsRepository) Create(ctx context.Context, click ClickModel)
 	return args.Get(0).(ClickModel), args.Error(1)
 }
 
-func (m *mockAnalyticsRepository) GetAll(ctx context.Context, offset, limit int) ([]ClickModel, int, error) {
-	args := m.Called(ctx, offset, limit)
+func (m *mockAnalyticsRepository) GetAll(ctx context.Context, params pagination.Params) ([]ClickModel, pagination.Page, error) {
+	args := m.Called(ctx, params)
 	var result []ClickModel
 	if args.Get(0) != nil {
 		result = args.Get(0).([]ClickModel)
 	}
-	return result, args.Int(1), args.Error(2)
+	return result, args.Get(1).(pagination.Page), args.Error(2)
 }
 
 func (m *mockAnalyticsRepository) GetAllByShortUrl(ctx context.Context, shortUrl string, offset, limit int) ([]ClickModel, int, error) {
@@ -43,6 +44,51 @@ func (m *mockAnalyticsRepository) GetByID(ctx context.Context, id types.ClickId)
 	return args.Get(0).(ClickModel), args.Error(1)
 }
 
+func (m *mockAnalyticsRepository) CountByVariant(ctx context.Context, shortID types.ShortId) ([]VariantClicks, error) {
+	args := m.Called(ctx, shortID)
+	var result []VariantClicks
+	if args.Get(0) != nil {
+		result = args.Get(0).([]VariantClicks)
+	}
+	return result, args.Error(1)
+}
+
+func (m *mockAnalyticsRepository) CountByCampaign(ctx context.Context, campaignID types.CampaignId) ([]ShortClicks, error) {
+	args := m.Called(ctx, campaignID)
+	var result []ShortClicks
+	if args.Get(0) != nil {
+		result = args.Get(0).([]ShortClicks)
+	}
+	return result, args.Error(1)
+}
+
+func (m *mockAnalyticsRepository) CountCampaignVisitors(ctx context.Context, campaignID types.CampaignId) (int, error) {
+	args := m.Called(ctx, campaignID)
+	return args.Int(0), args.Error(1)
+}
+
+type mockLocator struct {
+	mock.Mock
+}
+
+func (m *mockLocator) Country(ip string) (string, error) {
+	args := m.Called(ip)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockLocator) Close() error {
+	return nil
+}
+
+type mockCampaignLookup struct {
+	mock.Mock
+}
+
+func (m *mockCampaignLookup) GetCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) (shortener.CampaignModel, error) {
+	args := m.Called(userID, id)
+	return args.Get(0).(shortener.CampaignModel), args.Error(1)
+}
+
 // --- Create Tests ---
 func TestCreate_Success(t *testing.T) {
 	mockRepo := new(mockAnalyticsRepository)
@@ -59,6 +105,37 @@ func TestCreate_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestCreate_FillsCountry(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	locator := new(mockLocator)
+	service := NewAnalyticService(mockRepo, WithGeoLocator(locator))
+
+	locator.On("Country", "81.2.69.142").Return("GB", nil).Once()
+	mockRepo.On("Create", mock.Anything, ClickModel{ShortID: 1, IpAddress: "81.2.69.142", Country: "GB"}).
+		Return(ClickModel{ID: 1, ShortID: 1, IpAddress: "81.2.69.142", Country: "GB"}, nil).Once()
+
+	result, err := service.Create(nil, ClickModel{ShortID: 1, IpAddress: "81.2.69.142"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "GB", result.Country)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreate_UnknownCountryStillStored(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	locator := new(mockLocator)
+	service := NewAnalyticService(mockRepo, WithGeoLocator(locator))
+
+	locator.On("Country", "not-an-ip").Return("", errors.New("invalid IP")).Once()
+	mockRepo.On("Create", mock.Anything, ClickModel{ShortID: 1, IpAddress: "not-an-ip"}).
+		Return(ClickModel{ID: 1, ShortID: 1, IpAddress: "not-an-ip"}, nil).Once()
+
+	_, err := service.Create(nil, ClickModel{ShortID: 1, IpAddress: "not-an-ip"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestCreate_Failure(t *testing.T) {
 	mockRepo := new(mockAnalyticsRepository)
 	service := NewAnalyticService(mockRepo)
@@ -81,9 +158,9 @@ func TestGetAll_Success(t *testing.T) {
 
 	expectedClicks := []ClickModel{{ID: 1}, {ID: 2}}
 
-	mockRepo.On("GetAll", mock.Anything, 1, 1).Return(expectedClicks, 1, nil).Once()
+	mockRepo.On("GetAll", mock.Anything, pagination.Params{Offset: 1, Limit: 1}).Return(expectedClicks, pagination.Page{}, nil).Once()
 
-	result, _, err := service.GetAll(nil, 1, 1)
+	result, _, err := service.GetAll(nil, pagination.Params{Offset: 1, Limit: 1})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedClicks, result)
@@ -95,9 +172,9 @@ func TestGetAll_Failure_RepositoryError(t *testing.T) {
 	service := NewAnalyticService(mockRepo)
 
 	repoError := errors.New("repository error")
-	mockRepo.On("GetAll", mock.Anything, 1, 1).Return(nil, -1, repoError)
+	mockRepo.On("GetAll", mock.Anything, pagination.Params{Offset: 1, Limit: 1}).Return(nil, pagination.Page{}, repoError)
 
-	_, _, err := service.GetAll(nil, 1, 1)
+	_, _, err := service.GetAll(nil, pagination.Params{Offset: 1, Limit: 1})
 
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
@@ -107,9 +184,9 @@ func TestGetAll_Failure_NotFound(t *testing.T) {
 	mockRepo := new(mockAnalyticsRepository)
 	service := NewAnalyticService(mockRepo)
 
-	mockRepo.On("GetAll", mock.Anything, 1, 1).Return([]ClickModel{}, 1, nil).Once()
+	mockRepo.On("GetAll", mock.Anything, pagination.Params{Offset: 1, Limit: 1}).Return([]ClickModel{}, pagination.Page{}, nil).Once()
 
-	_, _, err := service.GetAll(nil, 1, 1)
+	_, _, err := service.GetAll(nil, pagination.Params{Offset: 1, Limit: 1})
 
 	assert.Error(t, err)
 	mockRepo.AssertExpectations(t)
@@ -173,9 +250,9 @@ func TestGetAllClicks_Success(t *testing.T) {
 	mockRepo := new(mockAnalyticsRepository)
 	service := NewAnalyticService(mockRepo)
 	expectedClicks := []ClickModel{{ID: 1}, {ID: 2}}
-	mockRepo.On("GetAll", ctx, 1, 1).Return(expectedClicks, 1, nil).Once()
+	mockRepo.On("GetAll", ctx, pagination.Params{Offset: 1, Limit: 1}).Return(expectedClicks, pagination.Page{}, nil).Once()
 
-	result, _, err := service.GetAllClicks(ctx, 1, 1)
+	result, _, err := service.GetAllClicks(ctx, pagination.Params{Offset: 1, Limit: 1})
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedClicks, result)
@@ -186,9 +263,9 @@ func TestGetAllClicks_Failure_NotFound(t *testing.T) {
 	ctx := context.Background()
 	mockRepo := new(mockAnalyticsRepository)
 	service := NewAnalyticService(mockRepo)
-	mockRepo.On("GetAll", ctx, 1, 1).Return([]ClickModel{}, 1, nil).Once()
+	mockRepo.On("GetAll", ctx, pagination.Params{Offset: 1, Limit: 1}).Return([]ClickModel{}, pagination.Page{}, nil).Once()
 
-	_, _, err := service.GetAllClicks(ctx, 1, 1)
+	_, _, err := service.GetAllClicks(ctx, pagination.Params{Offset: 1, Limit: 1})
 
 	assert.Error(t, err)
 	assert.ErrorIs(t, err, ErrClicksNotFound)
@@ -239,3 +316,78 @@ func TestGetByID_Failure_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, ErrClickNotFound)
 	mockRepo.AssertExpectations(t)
 }
+
+// --- GetVariantClicks Tests ---
+
+func TestGetVariantClicks_Success(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	service := NewAnalyticService(mockRepo)
+
+	counts := []VariantClicks{{VariantID: 1, Clicks: 7}, {VariantID: 2, Clicks: 3}}
+	mockRepo.On("CountByVariant", mock.Anything, types.ShortId(7)).Return(counts, nil).Once()
+
+	result, err := service.GetVariantClicks(context.Background(), 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, counts, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetVariantClicks_Failure_NotFound(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	service := NewAnalyticService(mockRepo)
+
+	mockRepo.On("CountByVariant", mock.Anything, types.ShortId(7)).Return(nil, nil).Once()
+
+	result, err := service.GetVariantClicks(context.Background(), 7)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrClicksNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+// --- GetCampaignClicks Tests ---
+
+func TestGetCampaignClicks_Success(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	campaigns := new(mockCampaignLookup)
+	service := NewAnalyticService(mockRepo, WithCampaignLookup(campaigns))
+
+	campaigns.On("GetCampaign", types.UserId(3), types.CampaignId(4)).Return(shortener.CampaignModel{ID: 4, UserID: 3}, nil)
+	shorts := []ShortClicks{{ShortID: 2, ShortUrl: "spring", Clicks: 9}, {ShortID: 1, ShortUrl: "sale", Clicks: 0}}
+	mockRepo.On("CountByCampaign", mock.Anything, types.CampaignId(4)).Return(shorts, nil).Once()
+	mockRepo.On("CountCampaignVisitors", mock.Anything, types.CampaignId(4)).Return(6, nil).Once()
+
+	result, err := service.GetCampaignClicks(context.Background(), 3, 4)
+
+	assert.NoError(t, err)
+	assert.Equal(t, CampaignClicks{CampaignID: 4, Clicks: 9, UniqueVisitors: 6, Shorts: shorts}, result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetCampaignClicks_Failure_NotFound(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	campaigns := new(mockCampaignLookup)
+	service := NewAnalyticService(mockRepo, WithCampaignLookup(campaigns))
+
+	campaigns.On("GetCampaign", types.UserId(3), types.CampaignId(4)).Return(shortener.CampaignModel{ID: 4, UserID: 3}, nil)
+	mockRepo.On("CountByCampaign", mock.Anything, types.CampaignId(4)).Return(nil, nil).Once()
+
+	_, err := service.GetCampaignClicks(context.Background(), 3, 4)
+
+	assert.ErrorIs(t, err, ErrClicksNotFound)
+	mockRepo.AssertNotCalled(t, "CountCampaignVisitors", mock.Anything, mock.Anything)
+}
+
+func TestGetCampaignClicks_Failure_NotOwned(t *testing.T) {
+	mockRepo := new(mockAnalyticsRepository)
+	campaigns := new(mockCampaignLookup)
+	service := NewAnalyticService(mockRepo, WithCampaignLookup(campaigns))
+
+	campaigns.On("GetCampaign", types.UserId(5), types.CampaignId(4)).Return(shortener.CampaignModel{}, shortener.ErrCampaignNotFound)
+
+	_, err := service.GetCampaignClicks(context.Background(), 5, 4)
+
+	assert.ErrorIs(t, err, shortener.ErrCampaignNotFound)
+	mockRepo.AssertNotCalled(t, "CountByCampaign", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+type DomainCreateRequest struct {
+	Host string `json:"host" validate:"required,fqdn"`
+}
+
+// VerificationRecord is the TXT record a user publishes to verify a domain.
+type VerificationRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type DomainResponse struct {
+	Id           types.DomainId     `json:"id"`
+	Host         string             `json:"host"`
+	Verified     bool               `json:"verified"`
+	VerifiedAt   *time.Time         `json:"verified_at,omitempty"`
+	Verification VerificationRecord `json:"verification"`
+}
+
+func NewDomainResponse(domain DomainModel) DomainResponse {
+	name, value := domain.VerificationRecord()
+	return DomainResponse{
+		Id:         domain.ID,
+		Host:       domain.Host,
+		Verified:   domain.IsVerified(),
+		VerifiedAt: domain.VerifiedAt,
+		Verification: VerificationRecord{
+			Type:  "TXT",
+			Name:  name,
+			Value: value,
+		},
+	}
+}
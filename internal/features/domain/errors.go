@@ -0,0 +1,13 @@
+package domain
+
+import "errors"
+
+var (
+	ErrDomainNotFound       = errors.New("Domain not found")
+	ErrDomainTaken          = errors.New("Domain is already registered")
+	ErrDomainInUse          = errors.New("Domain still has shorts")
+	ErrDomainNotOwned       = errors.New("Domain belongs to another user")
+	ErrDomainNotVerified    = errors.New("Domain is not verified")
+	ErrDomainVerifyFailed   = errors.New("Verification record not found")
+	ErrInvalidDomainRequest = errors.New("Invalid domain request")
+)
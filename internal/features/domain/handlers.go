@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/Kalmera74/Shorty/internal/middleware"
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+type DomainHandler struct {
+	service IDomainService
+}
+
+func NewDomainHandler(service IDomainService) *DomainHandler {
+	return &DomainHandler{service: service}
+}
+
+// GetDomains godoc
+// @Summary List the authenticated user's domains
+// @Tags domains
+// @Produce json
+// @Success 200 {array} DomainResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/domains [get]
+func (h *DomainHandler) GetDomains(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	domains, err := h.service.GetDomains(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]DomainResponse, 0, len(domains))
+	for _, domain := range domains {
+		responses = append(responses, NewDomainResponse(domain))
+	}
+	return c.JSON(responses)
+}
+
+// AddDomain godoc
+// @Summary Register a custom domain
+// @Description Registers an unverified domain and returns the DNS TXT record that verifies it
+// @Tags domains
+// @Accept json
+// @Produce json
+// @Param request body DomainCreateRequest true "DomainCreateRequest"
+// @Success 201 {object} DomainResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/me/domains [post]
+func (h *DomainHandler) AddDomain(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req DomainCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	domain, err := h.service.AddDomain(c.Context(), userID, req)
+	if err != nil {
+		return c.Status(domainErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(NewDomainResponse(domain))
+}
+
+// GetDomain godoc
+// @Summary Get one of the authenticated user's domains
+// @Tags domains
+// @Produce json
+// @Param id path int true "Domain ID"
+// @Success 200 {object} DomainResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/domains/{id} [get]
+func (h *DomainHandler) GetDomain(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	domain, err := h.service.GetDomainForUser(c.Context(), userID, types.DomainId(id))
+	if err != nil {
+		return c.Status(domainErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewDomainResponse(domain))
+}
+
+// VerifyDomain godoc
+// @Summary Verify a custom domain
+// @Description Checks the domain's DNS TXT record and marks it verified when the token matches
+// @Tags domains
+// @Produce json
+// @Param id path int true "Domain ID"
+// @Success 200 {object} DomainResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Router /api/v1/me/domains/{id}/verify [post]
+func (h *DomainHandler) VerifyDomain(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	domain, err := h.service.VerifyDomain(c.Context(), userID, types.DomainId(id))
+	if err != nil {
+		return c.Status(domainErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewDomainResponse(domain))
+}
+
+// DeleteDomain godoc
+// @Summary Remove a custom domain
+// @Description Refused while shorts on the domain exist; shorts in the trash are purged with it
+// @Tags domains
+// @Param id path int true "Domain ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/me/domains/{id} [delete]
+func (h *DomainHandler) DeleteDomain(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.service.DeleteDomain(c.Context(), userID, types.DomainId(id)); err != nil {
+		return c.Status(domainErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func domainErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidDomainRequest):
+		return fiber.StatusBadRequest
+	case errors.Is(err, ErrDomainNotOwned):
+		return fiber.StatusForbidden
+	case errors.Is(err, ErrDomainNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, ErrDomainTaken), errors.Is(err, ErrDomainInUse):
+		return fiber.StatusConflict
+	case errors.Is(err, ErrDomainVerifyFailed):
+		return fiber.StatusUnprocessableEntity
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"gorm.io/gorm"
+)
+
+// DomainModel is a user's claim on a host. Several users may claim the same
+// host, but only one claim can be verified, so an unverified claim never
+// keeps the real owner out.
+type DomainModel struct {
+	ID        types.DomainId `gorm:"primaryKey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	// DeletedAt is set while the owner is in the trash. A trashed verified
+	// domain keeps its host reserved, so the owner can be restored with it.
+	DeletedAt         gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	UserID            types.UserId   `gorm:"not null;index;uniqueIndex:idx_domains_user_host,priority:1" validate:"required,numeric,min=1"`
+	Host              string         `gorm:"not null;uniqueIndex:idx_domains_user_host,priority:2;uniqueIndex:idx_domains_verified_host,where:verified_at IS NOT NULL" validate:"required,fqdn"`
+	VerificationToken string         `gorm:"not null"`
+	VerifiedAt        *time.Time     `json:"verified_at,omitempty"`
+}
+
+// IsVerified reports whether the owner has proven control of the host.
+func (d DomainModel) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// VerificationRecord returns the name and value of the DNS TXT record that
+// proves control of the host.
+func (d DomainModel) VerificationRecord() (name, value string) {
+	return verificationRecordPrefix + d.Host, verificationValuePrefix + d.VerificationToken
+}
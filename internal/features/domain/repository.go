@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/types"
+	"gorm.io/gorm"
+)
+
+type IDomainRepository interface {
+	Create(ctx context.Context, domain DomainModel) (DomainModel, error)
+	GetById(ctx context.Context, id types.DomainId) (DomainModel, error)
+	GetByHost(ctx context.Context, host string) (DomainModel, error)
+	GetAllByUser(ctx context.Context, userID types.UserId) ([]DomainModel, error)
+	Update(ctx context.Context, domain DomainModel) error
+	Delete(ctx context.Context, id types.DomainId) error
+}
+
+type postgresDomainRepository struct {
+	db *gorm.DB
+}
+
+func NewDomainRepository(db *gorm.DB) IDomainRepository {
+	return &postgresDomainRepository{db: db}
+}
+
+func (r *postgresDomainRepository) Create(ctx context.Context, domain DomainModel) (DomainModel, error) {
+	result := r.db.WithContext(ctx).Create(&domain)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return DomainModel{}, fmt.Errorf("%w: %s", ErrDomainTaken, domain.Host)
+	}
+	if result.Error != nil {
+		return DomainModel{}, result.Error
+	}
+	return domain, nil
+}
+
+func (r *postgresDomainRepository) GetById(ctx context.Context, id types.DomainId) (DomainModel, error) {
+	var domain DomainModel
+	result := r.db.WithContext(ctx).First(&domain, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return DomainModel{}, fmt.Errorf("%w: %d", ErrDomainNotFound, id)
+	}
+	if result.Error != nil {
+		return DomainModel{}, result.Error
+	}
+	return domain, nil
+}
+
+// GetByHost returns the verified domain for host.
+func (r *postgresDomainRepository) GetByHost(ctx context.Context, host string) (DomainModel, error) {
+	var domain DomainModel
+	result := r.db.WithContext(ctx).Where("host = ? AND verified_at IS NOT NULL", host).First(&domain)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return DomainModel{}, fmt.Errorf("%w: %s", ErrDomainNotFound, host)
+	}
+	if result.Error != nil {
+		return DomainModel{}, result.Error
+	}
+	return domain, nil
+}
+
+func (r *postgresDomainRepository) GetAllByUser(ctx context.Context, userID types.UserId) ([]DomainModel, error) {
+	var domains []DomainModel
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&domains).Error; err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+func (r *postgresDomainRepository) Update(ctx context.Context, domain DomainModel) error {
+	result := r.db.WithContext(ctx).Model(&DomainModel{ID: domain.ID}).
+		Select("verification_token", "verified_at").
+		Updates(&domain)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %s", ErrDomainTaken, domain.Host)
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrDomainNotFound, domain.ID)
+	}
+	return nil
+}
+
+// Delete removes a domain that no short uses any more. Shorts on the domain
+// that are in the trash are purged with it, since they could not be
+// restored without it.
+func (r *postgresDomainRepository) Delete(ctx context.Context, id types.DomainId) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var shorts int64
+		if err := tx.Model(&shortener.ShortModel{}).Where("domain_id = ?", id).Count(&shorts).Error; err != nil {
+			return err
+		}
+		if shorts > 0 {
+			return fmt.Errorf("%w: %d shorts use domain %d", ErrDomainInUse, shorts, id)
+		}
+
+		if err := tx.Exec("DELETE FROM short_tags WHERE short_model_id IN (SELECT id FROM short_models WHERE domain_id = ?)", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("domain_id = ?", id).Delete(&shortener.ShortModel{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Delete(&DomainModel{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d", ErrDomainNotFound, id)
+		}
+		return nil
+	})
+}
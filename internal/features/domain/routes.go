@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"github.com/Kalmera74/Shorty/internal/middleware"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterRoutes(app *fiber.App, handler *DomainHandler) {
+	domains := app.Group("/api/v1/me/domains", middleware.Authenticate())
+	domains.Get("/", handler.GetDomains)
+	domains.Post("/", handler.AddDomain)
+	domains.Get("/:id", handler.GetDomain)
+	domains.Post("/:id/verify", handler.VerifyDomain)
+	domains.Delete("/:id", handler.DeleteDomain)
+}
@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	caching "github.com/Kalmera74/Shorty/pkg/cache"
+)
+
+const (
+	verificationRecordPrefix = "_shorty-verification."
+	verificationValuePrefix  = "shorty-verification="
+
+	hostCacheTTL = time.Minute * 5
+)
+
+// TXTResolver looks up DNS TXT records. *net.Resolver satisfies it; tests use
+// a stub so verification works offline.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type IDomainService interface {
+	AddDomain(ctx context.Context, userID types.UserId, req DomainCreateRequest) (DomainModel, error)
+	GetDomains(ctx context.Context, userID types.UserId) ([]DomainModel, error)
+	GetDomainForUser(ctx context.Context, userID types.UserId, id types.DomainId) (DomainModel, error)
+	VerifyDomain(ctx context.Context, userID types.UserId, id types.DomainId) (DomainModel, error)
+	DeleteDomain(ctx context.Context, userID types.UserId, id types.DomainId) error
+	DomainIDForHost(ctx context.Context, host string) (types.DomainId, error)
+	HostForDomain(ctx context.Context, id types.DomainId) (string, error)
+	CheckDomainUse(ctx context.Context, userID types.UserId, id types.DomainId) error
+}
+
+type domainService struct {
+	Repository IDomainRepository
+	Cacher     caching.ICacher
+	Resolver   TXTResolver
+}
+
+func NewDomainService(repository IDomainRepository, cacher caching.ICacher, resolver TXTResolver) IDomainService {
+	return &domainService{Repository: repository, Cacher: cacher, Resolver: resolver}
+}
+
+// AddDomain registers an unverified domain for userID. The returned domain
+// carries the token the user publishes in DNS before calling VerifyDomain.
+// Hosts somebody already verified cannot be claimed again.
+func (s *domainService) AddDomain(ctx context.Context, userID types.UserId, req DomainCreateRequest) (DomainModel, error) {
+	host := NormalizeHost(req.Host)
+	if host == "" {
+		return DomainModel{}, ErrInvalidDomainRequest
+	}
+
+	if _, err := s.Repository.GetByHost(ctx, host); err == nil {
+		return DomainModel{}, fmt.Errorf("%w: %s", ErrDomainTaken, host)
+	} else if !errors.Is(err, ErrDomainNotFound) {
+		return DomainModel{}, err
+	}
+
+	token, err := newVerificationToken()
+	if err != nil {
+		return DomainModel{}, err
+	}
+
+	return s.Repository.Create(ctx, DomainModel{
+		UserID:            userID,
+		Host:              host,
+		VerificationToken: token,
+	})
+}
+
+func (s *domainService) GetDomains(ctx context.Context, userID types.UserId) ([]DomainModel, error) {
+	return s.Repository.GetAllByUser(ctx, userID)
+}
+
+// GetDomainForUser returns the domain only when it belongs to userID.
+func (s *domainService) GetDomainForUser(ctx context.Context, userID types.UserId, id types.DomainId) (DomainModel, error) {
+	domain, err := s.Repository.GetById(ctx, id)
+	if err != nil {
+		return DomainModel{}, err
+	}
+	if domain.UserID != userID {
+		return DomainModel{}, fmt.Errorf("%w: %d", ErrDomainNotOwned, id)
+	}
+	return domain, nil
+}
+
+// VerifyDomain looks for the domain's TXT record and marks it verified when
+// the record carries the expected token. It fails with ErrDomainTaken when
+// another claim on the host was verified first.
+func (s *domainService) VerifyDomain(ctx context.Context, userID types.UserId, id types.DomainId) (DomainModel, error) {
+	domain, err := s.GetDomainForUser(ctx, userID, id)
+	if err != nil {
+		return DomainModel{}, err
+	}
+	if domain.IsVerified() {
+		return domain, nil
+	}
+
+	name, value := domain.VerificationRecord()
+	records, err := s.Resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return DomainModel{}, fmt.Errorf("%w: %v", ErrDomainVerifyFailed, err)
+	}
+
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == value {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return DomainModel{}, fmt.Errorf("%w: %s", ErrDomainVerifyFailed, name)
+	}
+
+	now := time.Now()
+	domain.VerifiedAt = &now
+	if err := s.Repository.Update(ctx, domain); err != nil {
+		return DomainModel{}, err
+	}
+
+	s.Cacher.Delete(ctx, domainByHostKey(domain.Host))
+	return domain, nil
+}
+
+// DeleteDomain removes a domain. It is refused with ErrDomainInUse while
+// shorts on the domain exist.
+func (s *domainService) DeleteDomain(ctx context.Context, userID types.UserId, id types.DomainId) error {
+	domain, err := s.GetDomainForUser(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.Repository.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.Cacher.Delete(ctx, domainByHostKey(domain.Host))
+	return nil
+}
+
+// DomainIDForHost returns the verified domain serving host, or 0 when host
+// is not a verified custom domain and the shared namespace applies. Both
+// answers are cached since every redirect asks.
+func (s *domainService) DomainIDForHost(ctx context.Context, host string) (types.DomainId, error) {
+	host = NormalizeHost(host)
+	if host == "" {
+		return 0, nil
+	}
+
+	key := domainByHostKey(host)
+	if cached, err := s.Cacher.Get(ctx, key); err == nil && cached != "" {
+		if id, err := strconv.ParseUint(cached, 10, 64); err == nil {
+			return types.DomainId(id), nil
+		}
+	}
+
+	var id types.DomainId
+	domain, err := s.Repository.GetByHost(ctx, host)
+	switch {
+	case err == nil && domain.IsVerified():
+		id = domain.ID
+	case err != nil && !errors.Is(err, ErrDomainNotFound):
+		return 0, err
+	}
+
+	s.Cacher.Set(ctx, key, strconv.FormatUint(uint64(id), 10), hostCacheTTL)
+	return id, nil
+}
+
+// HostForDomain returns the host of a domain, for building public links.
+func (s *domainService) HostForDomain(ctx context.Context, id types.DomainId) (string, error) {
+	domain, err := s.Repository.GetById(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return domain.Host, nil
+}
+
+// CheckDomainUse returns nil when userID may create shorts on the domain,
+// that is when they own it and it is verified.
+func (s *domainService) CheckDomainUse(ctx context.Context, userID types.UserId, id types.DomainId) error {
+	domain, err := s.GetDomainForUser(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !domain.IsVerified() {
+		return fmt.Errorf("%w: %s", ErrDomainNotVerified, domain.Host)
+	}
+	return nil
+}
+
+// NormalizeHost lower-cases host and strips any port and trailing dot, so a
+// Host header and a registered domain compare equal.
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+func newVerificationToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func domainByHostKey(host string) string {
+	return fmt.Sprintf("domain:byHost:%s", host)
+}
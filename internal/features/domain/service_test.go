@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mock Repository //
+type MockDomainRepository struct {
+	mock.Mock
+}
+
+func (m *MockDomainRepository) Create(ctx context.Context, domain DomainModel) (DomainModel, error) {
+	args := m.Called(domain)
+	return args.Get(0).(DomainModel), args.Error(1)
+}
+
+func (m *MockDomainRepository) GetById(ctx context.Context, id types.DomainId) (DomainModel, error) {
+	args := m.Called(id)
+	return args.Get(0).(DomainModel), args.Error(1)
+}
+
+func (m *MockDomainRepository) GetByHost(ctx context.Context, host string) (DomainModel, error) {
+	args := m.Called(host)
+	return args.Get(0).(DomainModel), args.Error(1)
+}
+
+func (m *MockDomainRepository) GetAllByUser(ctx context.Context, userID types.UserId) ([]DomainModel, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]DomainModel), args.Error(1)
+}
+
+func (m *MockDomainRepository) Update(ctx context.Context, domain DomainModel) error {
+	args := m.Called(domain)
+	return args.Error(0)
+}
+
+func (m *MockDomainRepository) Delete(ctx context.Context, id types.DomainId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+// Mock Redis //
+type MockRedis struct {
+	mock.Mock
+}
+
+func (m *MockRedis) Get(ctx context.Context, key string) (string, error) {
+	args := m.Called(key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRedis) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	m.Called(key, value, ttl)
+	return nil
+}
+
+func (m *MockRedis) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.Called(key)
+	}
+	return nil
+}
+
+func (m *MockRedis) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Stub Resolver //
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+// Tests //
+func TestAddDomain_NormalizesHost(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+
+	mockRepo.On("GetByHost", "go.brand.com").Return(DomainModel{}, ErrDomainNotFound)
+	mockRepo.On("Create", mock.MatchedBy(func(d DomainModel) bool {
+		return d.UserID == 1 && d.Host == "go.brand.com" && len(d.VerificationToken) == 32 && d.VerifiedAt == nil
+	})).Return(DomainModel{ID: 1, UserID: 1, Host: "go.brand.com"}, nil)
+
+	service := NewDomainService(mockRepo, nil, nil)
+	domain, err := service.AddDomain(nil, 1, DomainCreateRequest{Host: "Go.Brand.com."})
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.DomainId(1), domain.ID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestVerifyDomain_Success(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	mockRedis := new(MockRedis)
+	domain := DomainModel{ID: 4, UserID: 1, Host: "go.brand.com", VerificationToken: "abc"}
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(domain, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(d DomainModel) bool { return d.IsVerified() })).Return(nil)
+	mockRedis.On("Delete", "domain:byHost:go.brand.com").Return()
+
+	resolver := stubResolver{"_shorty-verification.go.brand.com": {"v=spf1 -all", "shorty-verification=abc"}}
+	service := NewDomainService(mockRepo, mockRedis, resolver)
+	verified, err := service.VerifyDomain(nil, 1, 4)
+
+	assert.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+	mockRepo.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestAddDomain_VerifiedByAnotherUser(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+
+	verifiedAt := time.Now()
+	mockRepo.On("GetByHost", "go.brand.com").Return(DomainModel{ID: 2, UserID: 2, Host: "go.brand.com", VerifiedAt: &verifiedAt}, nil)
+
+	service := NewDomainService(mockRepo, nil, nil)
+	_, err := service.AddDomain(nil, 1, DomainCreateRequest{Host: "go.brand.com"})
+
+	assert.ErrorIs(t, err, ErrDomainTaken)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestVerifyDomain_VerifiedByAnotherClaimFirst(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	domain := DomainModel{ID: 4, UserID: 1, Host: "go.brand.com", VerificationToken: "abc"}
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(domain, nil)
+	mockRepo.On("Update", mock.Anything).Return(ErrDomainTaken)
+
+	resolver := stubResolver{"_shorty-verification.go.brand.com": {"shorty-verification=abc"}}
+	service := NewDomainService(mockRepo, nil, resolver)
+	_, err := service.VerifyDomain(nil, 1, 4)
+
+	assert.ErrorIs(t, err, ErrDomainTaken)
+}
+
+func TestVerifyDomain_WrongToken(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	domain := DomainModel{ID: 4, UserID: 1, Host: "go.brand.com", VerificationToken: "abc"}
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(domain, nil)
+
+	resolver := stubResolver{"_shorty-verification.go.brand.com": {"shorty-verification=other"}}
+	service := NewDomainService(mockRepo, nil, resolver)
+	_, err := service.VerifyDomain(nil, 1, 4)
+
+	assert.ErrorIs(t, err, ErrDomainVerifyFailed)
+	mockRepo.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestVerifyDomain_NotOwned(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(DomainModel{ID: 4, UserID: 2, Host: "go.brand.com"}, nil)
+
+	service := NewDomainService(mockRepo, nil, stubResolver{})
+	_, err := service.VerifyDomain(nil, 1, 4)
+
+	assert.ErrorIs(t, err, ErrDomainNotOwned)
+}
+
+func TestDomainIDForHost_Verified(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	mockRedis := new(MockRedis)
+	now := time.Now()
+
+	mockRedis.On("Get", "domain:byHost:go.brand.com").Return("", redis.Nil)
+	mockRepo.On("GetByHost", "go.brand.com").Return(DomainModel{ID: 4, Host: "go.brand.com", VerifiedAt: &now}, nil)
+	mockRedis.On("Set", "domain:byHost:go.brand.com", "4", hostCacheTTL).Return()
+
+	service := NewDomainService(mockRepo, mockRedis, nil)
+	id, err := service.DomainIDForHost(nil, "GO.brand.com:443")
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.DomainId(4), id)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestDomainIDForHost_UnverifiedUsesSharedNamespace(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	mockRedis := new(MockRedis)
+
+	mockRedis.On("Get", "domain:byHost:go.brand.com").Return("", redis.Nil)
+	mockRepo.On("GetByHost", "go.brand.com").Return(DomainModel{ID: 4, Host: "go.brand.com"}, nil)
+	mockRedis.On("Set", "domain:byHost:go.brand.com", "0", hostCacheTTL).Return()
+
+	service := NewDomainService(mockRepo, mockRedis, nil)
+	id, err := service.DomainIDForHost(nil, "go.brand.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.DomainId(0), id)
+}
+
+func TestDomainIDForHost_CacheHit(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+	mockRedis := new(MockRedis)
+
+	mockRedis.On("Get", "domain:byHost:go.brand.com").Return("4", nil)
+
+	service := NewDomainService(mockRepo, mockRedis, nil)
+	id, err := service.DomainIDForHost(nil, "go.brand.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.DomainId(4), id)
+	mockRepo.AssertNotCalled(t, "GetByHost", mock.Anything)
+}
+
+func TestCheckDomainUse_Unverified(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(DomainModel{ID: 4, UserID: 1, Host: "go.brand.com"}, nil)
+
+	service := NewDomainService(mockRepo, nil, nil)
+	err := service.CheckDomainUse(nil, 1, 4)
+
+	assert.ErrorIs(t, err, ErrDomainNotVerified)
+}
+
+func TestDeleteDomain_InUse(t *testing.T) {
+	mockRepo := new(MockDomainRepository)
+
+	mockRepo.On("GetById", types.DomainId(4)).Return(DomainModel{ID: 4, UserID: 1, Host: "go.brand.com"}, nil)
+	mockRepo.On("Delete", types.DomainId(4)).Return(ErrDomainInUse)
+
+	service := NewDomainService(mockRepo, nil, nil)
+	err := service.DeleteDomain(nil, 1, 4)
+
+	assert.ErrorIs(t, err, ErrDomainInUse)
+}
@@ -0,0 +1,117 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// CampaignModel groups a user's shorts for marketing and holds the UTM
+// parameters merged into the destination of every short created under it.
+// Campaign names are unique per user.
+type CampaignModel struct {
+	ID          types.CampaignId `gorm:"primaryKey"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	UserID      types.UserId     `gorm:"not null;uniqueIndex:idx_campaigns_user_name,priority:1"`
+	Name        string           `gorm:"not null;uniqueIndex:idx_campaigns_user_name,priority:2"`
+	UtmSource   string           `json:"utm_source"`
+	UtmMedium   string           `json:"utm_medium"`
+	UtmCampaign string           `json:"utm_campaign"`
+}
+
+// utmParams returns the campaign's UTM parameters that are set.
+func (c CampaignModel) utmParams() url.Values {
+	params := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   c.UtmSource,
+		"utm_medium":   c.UtmMedium,
+		"utm_campaign": c.UtmCampaign,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	return params
+}
+
+// mergeUTM adds the campaign's UTM parameters to raw. Parameters raw already
+// carries are kept, so a single link can still deviate from its campaign.
+func mergeUTM(raw string, campaign CampaignModel) string {
+	params := campaign.utmParams()
+	if len(params) == 0 {
+		return raw
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	query := target.Query()
+	added := false
+	for key, values := range params {
+		if query.Has(key) {
+			continue
+		}
+		query[key] = values
+		added = true
+	}
+	if !added {
+		return raw
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// CreateCampaign creates a campaign. Its utm_campaign defaults to its name.
+func (s *shortService) CreateCampaign(ctx context.Context, userID types.UserId, req CampaignCreateRequest) (CampaignModel, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return CampaignModel{}, fmt.Errorf("%w: empty campaign name", ErrInvalidUpdateRequest)
+	}
+
+	campaign := CampaignModel{
+		UserID:      userID,
+		Name:        name,
+		UtmSource:   strings.TrimSpace(req.UtmSource),
+		UtmMedium:   strings.TrimSpace(req.UtmMedium),
+		UtmCampaign: strings.TrimSpace(req.UtmCampaign),
+	}
+	if campaign.UtmCampaign == "" {
+		campaign.UtmCampaign = name
+	}
+	return s.Repository.CreateCampaign(ctx, campaign)
+}
+
+func (s *shortService) GetCampaigns(ctx context.Context, userID types.UserId) ([]CampaignModel, error) {
+	return s.Repository.ListCampaigns(ctx, userID)
+}
+
+func (s *shortService) GetCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) (CampaignModel, error) {
+	return s.campaignForUser(ctx, userID, id)
+}
+
+func (s *shortService) DeleteCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) error {
+	if _, err := s.campaignForUser(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Repository.DeleteCampaign(ctx, id)
+}
+
+// campaignForUser returns the campaign when it belongs to userID. Other
+// users' campaigns are reported as missing rather than forbidden.
+func (s *shortService) campaignForUser(ctx context.Context, userID types.UserId, id types.CampaignId) (CampaignModel, error) {
+	campaign, err := s.Repository.GetCampaign(ctx, id)
+	if err != nil {
+		return CampaignModel{}, err
+	}
+	if campaign.UserID != userID {
+		return CampaignModel{}, fmt.Errorf("%w: %d", ErrCampaignNotFound, id)
+	}
+	return campaign, nil
+}
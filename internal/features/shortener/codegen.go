@@ -0,0 +1,183 @@
+package shortener
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	caching "github.com/Kalmera74/Shorty/pkg/cache"
+)
+
+const (
+	DefaultCodeLength     = 8
+	DefaultCodeMaxRetries = 5
+
+	base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	codeCounterKey = "short:codeCounter"
+)
+
+// CodeGenerator produces candidate short codes for ShortenURL. attempt starts
+// at 0 and grows every time a candidate collides with an existing code, so
+// deterministic strategies can derive a different code on retry.
+type CodeGenerator interface {
+	Generate(ctx context.Context, req ShortenRequest, attempt int) (string, error)
+}
+
+// Counter hands out monotonically increasing numbers for the counter strategy.
+type Counter interface {
+	Next(ctx context.Context) (uint64, error)
+}
+
+type randomCodeGenerator struct {
+	length int
+}
+
+// NewRandomCodeGenerator returns a generator that draws every character from
+// crypto/rand. Collisions are resolved by the service retrying.
+func NewRandomCodeGenerator(length int) CodeGenerator {
+	return &randomCodeGenerator{length: normalizeCodeLength(length)}
+}
+
+func (g *randomCodeGenerator) Generate(ctx context.Context, req ShortenRequest, attempt int) (string, error) {
+	// Reject bytes above the largest multiple of 62 to avoid modulo bias.
+	const limit = 256 - 256%len(base62Alphabet)
+
+	code := make([]byte, 0, g.length)
+	buf := make([]byte, g.length*2)
+	for len(code) < g.length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("could not read random bytes: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			code = append(code, base62Alphabet[int(b)%len(base62Alphabet)])
+			if len(code) == g.length {
+				break
+			}
+		}
+	}
+	return string(code), nil
+}
+
+type hashCodeGenerator struct {
+	length int
+	salt   string
+}
+
+// NewHashCodeGenerator returns a generator that derives the code from a salted
+// hash of the user, the URL and the attempt number, so the same user and URL
+// always map to the same first candidate while different users do not collide.
+func NewHashCodeGenerator(length int, salt string) CodeGenerator {
+	return &hashCodeGenerator{length: normalizeCodeLength(length), salt: salt}
+}
+
+func (g *hashCodeGenerator) Generate(ctx context.Context, req ShortenRequest, attempt int) (string, error) {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s|%d", g.salt, req.UserID, req.Url, attempt)))
+	code := encodeBase62(new(big.Int).SetBytes(sum[:]))
+	return padCode(code, g.length)[:g.length], nil
+}
+
+type counterCodeGenerator struct {
+	length  int
+	counter Counter
+}
+
+// NewCounterCodeGenerator returns a generator that base62-encodes the next
+// value of counter. Codes are left padded to length and grow past it once the
+// counter outgrows the configured length.
+func NewCounterCodeGenerator(length int, counter Counter) CodeGenerator {
+	return &counterCodeGenerator{length: normalizeCodeLength(length), counter: counter}
+}
+
+func (g *counterCodeGenerator) Generate(ctx context.Context, req ShortenRequest, attempt int) (string, error) {
+	n, err := g.counter.Next(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not advance code counter: %w", err)
+	}
+	return padCode(encodeBase62(new(big.Int).SetUint64(n)), g.length), nil
+}
+
+type cacheCounter struct {
+	cacher caching.ICacher
+	key    string
+}
+
+// NewCacheCounter returns a Counter backed by an atomic increment in the
+// cache, so that every API instance shares the same sequence.
+func NewCacheCounter(cacher caching.ICacher, key string) Counter {
+	return &cacheCounter{cacher: cacher, key: key}
+}
+
+func (c *cacheCounter) Next(ctx context.Context) (uint64, error) {
+	n, err := c.cacher.Increment(ctx, c.key, 0)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// NewCodeGeneratorFromEnv builds the generator selected by SHORT_CODE_STRATEGY
+// (random, counter or hash) with the length from SHORT_CODE_LENGTH.
+func NewCodeGeneratorFromEnv(cacher caching.ICacher) (CodeGenerator, error) {
+	length := DefaultCodeLength
+	if raw := os.Getenv("SHORT_CODE_LENGTH"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return nil, fmt.Errorf("invalid SHORT_CODE_LENGTH %q", raw)
+		}
+		length = parsed
+	}
+
+	strategy := strings.ToLower(os.Getenv("SHORT_CODE_STRATEGY"))
+	switch strategy {
+	case "", "random":
+		return NewRandomCodeGenerator(length), nil
+	case "counter":
+		return NewCounterCodeGenerator(length, NewCacheCounter(cacher, codeCounterKey)), nil
+	case "hash":
+		return NewHashCodeGenerator(length, os.Getenv("SHORT_CODE_SALT")), nil
+	default:
+		return nil, fmt.Errorf("unknown SHORT_CODE_STRATEGY %q", strategy)
+	}
+}
+
+func normalizeCodeLength(length int) int {
+	if length < 1 {
+		return DefaultCodeLength
+	}
+	return length
+}
+
+func encodeBase62(n *big.Int) string {
+	if n.Sign() == 0 {
+		return string(base62Alphabet[0])
+	}
+
+	base := big.NewInt(int64(len(base62Alphabet)))
+	rem := new(big.Int)
+	n = new(big.Int).Set(n)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, rem)
+		out = append(out, base62Alphabet[rem.Int64()])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func padCode(code string, length int) string {
+	if len(code) >= length {
+		return code
+	}
+	return strings.Repeat(string(base62Alphabet[0]), length-len(code)) + code
+}
@@ -0,0 +1,83 @@
+package shortener
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fixedCounter struct {
+	next uint64
+}
+
+func (c *fixedCounter) Next(ctx context.Context) (uint64, error) {
+	c.next++
+	return c.next, nil
+}
+
+func TestRandomCodeGenerator_LengthAndAlphabet(t *testing.T) {
+	generator := NewRandomCodeGenerator(12)
+
+	for i := 0; i < 50; i++ {
+		code, err := generator.Generate(context.Background(), ShortenRequest{}, 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, code, 12)
+		assert.Regexp(t, "^[0-9A-Za-z]+$", code)
+	}
+}
+
+func TestHashCodeGenerator_SaltedPerUserAndAttempt(t *testing.T) {
+	generator := NewHashCodeGenerator(8, "pepper")
+	req := ShortenRequest{UserID: 1, Url: "https://example.com"}
+	other := ShortenRequest{UserID: 2, Url: "https://example.com"}
+
+	first, _ := generator.Generate(context.Background(), req, 0)
+	again, _ := generator.Generate(context.Background(), req, 0)
+	retry, _ := generator.Generate(context.Background(), req, 1)
+	otherUser, _ := generator.Generate(context.Background(), other, 0)
+	otherSalt, _ := NewHashCodeGenerator(8, "salt").Generate(context.Background(), req, 0)
+
+	assert.Len(t, first, 8)
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, retry)
+	assert.NotEqual(t, first, otherUser)
+	assert.NotEqual(t, first, otherSalt)
+}
+
+func TestCounterCodeGenerator_Base62Padded(t *testing.T) {
+	generator := NewCounterCodeGenerator(4, &fixedCounter{next: 61})
+
+	first, err := generator.Generate(context.Background(), ShortenRequest{}, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "0010", first)
+
+	second, _ := generator.Generate(context.Background(), ShortenRequest{}, 0)
+	assert.Equal(t, "0011", second)
+}
+
+func TestCacheCounter_UsesIncrement(t *testing.T) {
+	mockRedis := new(MockRedis)
+	mockRedis.On("Increment", mock.Anything, codeCounterKey, mock.Anything).Return(int64(42), nil)
+
+	n, err := NewCacheCounter(mockRedis, codeCounterKey).Next(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), n)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestNewCodeGeneratorFromEnv(t *testing.T) {
+	t.Setenv("SHORT_CODE_STRATEGY", "hash")
+	t.Setenv("SHORT_CODE_LENGTH", "6")
+
+	generator, err := NewCodeGeneratorFromEnv(nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &hashCodeGenerator{}, generator)
+
+	t.Setenv("SHORT_CODE_STRATEGY", "sequential")
+	_, err = NewCodeGeneratorFromEnv(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,205 @@
+package shortener
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// csvExportBatchSize is how many shorts ExportCSV loads from the database at a
+// time.
+const csvExportBatchSize = 500
+
+// csvExportHeader lists the exported columns. The first four use the names
+// ImportCSV understands, so an export can be imported again as is.
+var csvExportHeader = []string{
+	"original_url", "custom_short_url", "expires_at", "tags",
+	"id", "max_clicks", "click_count", "enabled", "created_at",
+}
+
+// csvColumns holds the position of every column ImportCSV reads, -1 when the
+// file does not have it.
+type csvColumns struct {
+	originalUrl    int
+	customShortUrl int
+	expiresAt      int
+	tags           int
+}
+
+// parseCSVHeader finds the known columns in header. Other shorteners name
+// them differently, so a few aliases are accepted and unknown columns are
+// ignored.
+func parseCSVHeader(header []string) (csvColumns, error) {
+	columns := csvColumns{originalUrl: -1, customShortUrl: -1, expiresAt: -1, tags: -1}
+
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "original_url", "url":
+			columns.originalUrl = i
+		case "custom_short_url", "short_url", "slug":
+			columns.customShortUrl = i
+		case "expires_at", "expiry":
+			columns.expiresAt = i
+		case "tags":
+			columns.tags = i
+		}
+	}
+
+	if columns.originalUrl < 0 {
+		return csvColumns{}, fmt.Errorf("%w: missing original_url column", ErrInvalidCSV)
+	}
+	return columns, nil
+}
+
+// request builds the ShortenRequest for one CSV record.
+func (columns csvColumns) request(record []string, userID types.UserId) (ShortenRequest, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	req := ShortenRequest{
+		UserID: userID,
+		Url:    field(columns.originalUrl),
+	}
+
+	if slug := field(columns.customShortUrl); slug != "" {
+		req.CustomShortUrl = &slug
+	}
+
+	if expiry := field(columns.expiresAt); expiry != "" {
+		expiresAt, err := parseCSVTime(expiry)
+		if err != nil {
+			return ShortenRequest{}, fmt.Errorf("%w: invalid expires_at %q", ErrInvalidCSVRow, expiry)
+		}
+		req.ExpiresAt = &expiresAt
+	}
+
+	// Tags are separated by semicolons, or by commas inside a quoted field.
+	req.Tags = strings.FieldsFunc(field(columns.tags), func(r rune) bool {
+		return r == ';' || r == ','
+	})
+
+	return req, nil
+}
+
+// parseCSVTime accepts RFC 3339 timestamps and plain dates, which are read as
+// midnight UTC.
+func parseCSVTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// ImportCSV reads shorts for userID from r one record at a time and creates
+// each through ShortenURL as soon as it is read, so the rows are neither
+// collected nor inserted together. Rows that fail are reported by line number
+// and do not stop the import.
+func (s *shortService) ImportCSV(ctx context.Context, userID types.UserId, r io.Reader) (ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+	columns, err := parseCSVHeader(header)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result := ImportResult{Errors: []ImportRowError{}}
+	fail := func(line int, err error) {
+		result.Failed++
+		result.Errors = append(result.Errors, ImportRowError{Row: line, Code: ErrorCode(err), Error: err.Error()})
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return result, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+			}
+			fail(parseErr.StartLine, fmt.Errorf("%w: %v", ErrInvalidCSVRow, parseErr.Err))
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		req, err := columns.request(record, userID)
+		if err != nil {
+			fail(line, err)
+			continue
+		}
+
+		if _, err := s.shortenOne(ctx, req); err != nil {
+			fail(line, err)
+			continue
+		}
+		result.Imported++
+	}
+
+	return result, nil
+}
+
+// ExportCSV writes every short of userID, or every short when userID is nil,
+// to w as CSV. Shorts are read and written batch by batch.
+func (s *shortService) ExportCSV(ctx context.Context, userID *types.UserId, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportHeader); err != nil {
+		return err
+	}
+
+	err := s.Repository.Iterate(ctx, userID, csvExportBatchSize, func(batch []ShortModel) error {
+		for _, short := range batch {
+			if err := writer.Write(csvRecord(short)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRecord(short ShortModel) []string {
+	expiresAt := ""
+	if short.ExpiresAt != nil {
+		expiresAt = short.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	maxClicks := ""
+	if short.MaxClicks != nil {
+		maxClicks = strconv.Itoa(*short.MaxClicks)
+	}
+
+	return []string{
+		short.OriginalUrl,
+		short.ShortUrl,
+		expiresAt,
+		strings.Join(short.TagNames(), ";"),
+		strconv.FormatUint(uint64(short.ID), 10),
+		maxClicks,
+		strconv.Itoa(short.ClickCount),
+		strconv.FormatBool(!short.Disabled),
+		short.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,50 @@
+package shortener
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultRedirectStatus is used for shorts that do not choose their own. A
+// temporary redirect keeps browsers from caching the destination forever, so
+// edits and click analytics keep working.
+const DefaultRedirectStatus = fiber.StatusFound
+
+// redirectStatus returns the status a short redirects with, falling back to
+// the default for shorts stored before the status was configurable.
+func redirectStatus(short ShortModel) int {
+	switch short.RedirectStatus {
+	case fiber.StatusMovedPermanently, fiber.StatusFound, fiber.StatusTemporaryRedirect, fiber.StatusPermanentRedirect:
+		return short.RedirectStatus
+	default:
+		return DefaultRedirectStatus
+	}
+}
+
+// mergeQuery adds the parameters of the incoming query string to destination.
+// A parameter present in both takes the incoming values, so a visitor's UTM
+// tags replace the defaults baked into the destination.
+func mergeQuery(destination, incoming string) string {
+	if incoming == "" {
+		return destination
+	}
+
+	extra, err := url.ParseQuery(incoming)
+	if err != nil || len(extra) == 0 {
+		return destination
+	}
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	query := target.Query()
+	for key, values := range extra {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
@@ -0,0 +1,53 @@
+package shortener
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// IDomainLookup resolves the custom domains shorts can be served from. It is
+// implemented by the domain feature; without one only the shared host is
+// served.
+type IDomainLookup interface {
+	// DomainIDForHost returns the verified domain serving host, or 0 when
+	// host is not a custom domain.
+	DomainIDForHost(ctx context.Context, host string) (types.DomainId, error)
+	HostForDomain(ctx context.Context, id types.DomainId) (string, error)
+	// CheckDomainUse returns nil when userID may create shorts on the domain.
+	CheckDomainUse(ctx context.Context, userID types.UserId, id types.DomainId) error
+}
+
+// WithDomainLookup enables custom domains.
+func WithDomainLookup(domains IDomainLookup) ShortServiceOption {
+	return func(s *shortService) {
+		s.Domains = domains
+	}
+}
+
+func (s *shortService) domainIDForHost(ctx context.Context, host string) (types.DomainId, error) {
+	if s.Domains == nil || host == "" {
+		return 0, nil
+	}
+	return s.Domains.DomainIDForHost(ctx, host)
+}
+
+// ShortLink returns the public URL of short. Shorts on a custom domain use
+// that host with the scheme of baseURL; the rest are served under baseURL.
+func (s *shortService) ShortLink(ctx context.Context, short ShortModel, baseURL string) string {
+	base := strings.TrimRight(baseURL, "/")
+
+	if short.DomainID != 0 && s.Domains != nil {
+		if host, err := s.Domains.HostForDomain(ctx, short.DomainID); err == nil {
+			scheme := "https"
+			if parsed, err := url.Parse(base); err == nil && parsed.Scheme != "" {
+				scheme = parsed.Scheme
+			}
+			base = scheme + "://" + host
+		}
+	}
+
+	return base + "/" + short.ShortUrl
+}
@@ -1,27 +1,397 @@
 package shortener
 
-import "github.com/Kalmera74/Shorty/internal/types"
+import (
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
 
 type ShortenRequest struct {
-	UserID         types.UserId `json:"user_id" validate:"required,numeric,min=1"`
+	// UserID is taken from the token; only admins may create shorts for others.
+	UserID         types.UserId `json:"user_id,omitempty" validate:"required,numeric,min=1"`
 	Url            string       `json:"original_url" validate:"required,url"`
-	CustomShortUrl *string      `json:"custom_short_url,omitempty"`
+	CustomShortUrl *string      `json:"custom_short_url,omitempty" validate:"omitempty,min=1,max=64"`
+	ExpiresAt      *time.Time   `json:"expires_at,omitempty"`
+	MaxClicks      *int         `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	Password       *string      `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+	RedirectStatus *int         `json:"redirect_status,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	ForwardQuery   bool         `json:"forward_query,omitempty"`
+	// AlwaysPreview shows visitors the preview page instead of redirecting.
+	AlwaysPreview bool `json:"always_preview,omitempty"`
+	// DomainID picks one of the user's verified custom domains, 0 for the
+	// shared host.
+	DomainID types.DomainId  `json:"domain_id,omitempty"`
+	Tags     []string        `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=32"`
+	FolderID *types.FolderId `json:"folder_id,omitempty"`
+	// CampaignID creates the short under one of the user's campaigns, whose
+	// UTM parameters are merged into Url.
+	CampaignID *types.CampaignId `json:"campaign_id,omitempty"`
+	// ActivateAt keeps the short from redirecting until the given time.
+	ActivateAt    *time.Time `json:"activate_at,omitempty"`
+	ComingSoonUrl *string    `json:"coming_soon_url,omitempty" validate:"omitempty,url"`
+	// FallbackUrl is used instead of Url while Url is found broken.
+	FallbackUrl *string `json:"fallback_url,omitempty" validate:"omitempty,url"`
+	// Destinations override Url during their time windows.
+	Destinations []DestinationRequest `json:"destinations,omitempty" validate:"omitempty,max=20,dive"`
+	// Rules route visitors by device, language and country, first match
+	// wins.
+	Rules []RedirectRuleRequest `json:"rules,omitempty" validate:"omitempty,max=20,dive"`
+	// Variants split visitors between destinations for an A/B test.
+	Variants []VariantRequest `json:"variants,omitempty" validate:"omitempty,max=10,dive"`
+}
+
+// DestinationRequest schedules a destination. Either bound may be left out
+// for a window that is open on that side.
+type DestinationRequest struct {
+	Url      string     `json:"url" validate:"required,url"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// RedirectRuleRequest describes a redirect rule. At least one of OS, Device,
+// Language and Country must be set.
+type RedirectRuleRequest struct {
+	OS       string `json:"os,omitempty" validate:"omitempty,oneof=ios android windows macos linux chromeos other"`
+	Device   string `json:"device,omitempty" validate:"omitempty,oneof=mobile tablet desktop bot"`
+	Language string `json:"language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Country  string `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	Url      string `json:"url" validate:"required,url"`
+}
+
+type RedirectRuleResponse struct {
+	OS       string `json:"os,omitempty"`
+	Device   string `json:"device,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Url      string `json:"url"`
+}
+
+// VariantRequest describes an A/B variant. Name defaults to a letter by
+// position and Weight to 1.
+type VariantRequest struct {
+	Name   string `json:"name,omitempty" validate:"omitempty,max=32"`
+	Url    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight,omitempty" validate:"omitempty,min=1,max=1000"`
+}
+
+type VariantResponse struct {
+	Id     types.VariantId `json:"id"`
+	Name   string          `json:"name"`
+	Url    string          `json:"url"`
+	Weight int             `json:"weight"`
+}
+
+type DestinationResponse struct {
+	Url      string     `json:"url"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+type BulkShortenRequest struct {
+	Items []ShortenRequest `json:"items" validate:"required,min=1"`
+	// Atomic creates either every item or none of them.
+	Atomic bool `json:"atomic,omitempty"`
+}
+
+type BulkShortenItemResult struct {
+	Index int            `json:"index"`
+	Short *ShortResponse `json:"short,omitempty"`
+	Code  string         `json:"code,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+type BulkShortenResponse struct {
+	Succeeded int                     `json:"succeeded"`
+	Failed    int                     `json:"failed"`
+	Results   []BulkShortenItemResult `json:"results"`
+}
+
+// QRCodeRequest holds the query options of a QR code image. Colours are
+// written as rrggbb, with or without a leading #.
+type QRCodeRequest struct {
+	Format     string `query:"format" validate:"omitempty,oneof=png svg PNG SVG"`
+	Size       int    `query:"size" validate:"omitempty,min=64,max=2048"`
+	Level      string `query:"ec" validate:"omitempty,oneof=L M Q H l m q h"`
+	Foreground string `query:"fg"`
+	Background string `query:"bg"`
+}
+
+type ImportResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError describes a rejected CSV row. Row is the line number in the
+// uploaded file, counting the header as line 1.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+type UpdateShortRequest struct {
+	OriginalUrl    *string `json:"original_url,omitempty" validate:"omitempty,url"`
+	ShortUrl       *string `json:"short_url,omitempty" validate:"omitempty,min=1,max=64"`
+	Enabled        *bool   `json:"enabled,omitempty"`
+	RedirectStatus *int    `json:"redirect_status,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+	ForwardQuery   *bool   `json:"forward_query,omitempty"`
+	AlwaysPreview  *bool   `json:"always_preview,omitempty"`
+	// Tags replaces the short's tags; an empty list removes them all.
+	Tags *[]string `json:"tags,omitempty" validate:"omitempty,max=20,dive,min=1,max=32"`
+	// FolderID moves the short into a folder, 0 takes it out of its folder.
+	FolderID *types.FolderId `json:"folder_id,omitempty"`
+	// ActivateAt moves the activation time; a time in the past activates the
+	// short right away.
+	ActivateAt *time.Time `json:"activate_at,omitempty"`
+	// ComingSoonUrl replaces the short's coming soon URL, empty removes it.
+	ComingSoonUrl *string `json:"coming_soon_url,omitempty" validate:"omitempty,len=0|url"`
+	// FallbackUrl replaces the short's fallback URL, empty removes it.
+	FallbackUrl *string `json:"fallback_url,omitempty" validate:"omitempty,len=0|url"`
+	// Destinations replaces the scheduled destinations; an empty list removes
+	// them all.
+	Destinations *[]DestinationRequest `json:"destinations,omitempty" validate:"omitempty,max=20,dive"`
+	// Rules replaces the redirect rules; an empty list removes them all.
+	Rules *[]RedirectRuleRequest `json:"rules,omitempty" validate:"omitempty,max=20,dive"`
+	// Variants replaces the A/B variants, which starts a new test; an empty
+	// list removes them all.
+	Variants *[]VariantRequest `json:"variants,omitempty" validate:"omitempty,max=10,dive"`
+}
+
+// ShortHistoryResponse describes one change of a short.
+type ShortHistoryResponse struct {
+	Version      int           `json:"version"`
+	EditorID     types.UserId  `json:"editor_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	RolledBackTo *int          `json:"rolled_back_to,omitempty"`
+	Old          ShortSnapshot `json:"old"`
+	New          ShortSnapshot `json:"new"`
+}
+
+func NewShortHistoryResponse(entry ShortHistoryModel) ShortHistoryResponse {
+	return ShortHistoryResponse{
+		Version:      entry.Version,
+		EditorID:     entry.EditorID,
+		CreatedAt:    entry.CreatedAt,
+		RolledBackTo: entry.RolledBackTo,
+		Old:          entry.Old,
+		New:          entry.New,
+	}
+}
+
+// PreviewResponse describes where a short leads, for the preview page.
+type PreviewResponse struct {
+	ShortUrl    string    `json:"short_url"`
+	Destination string    `json:"destination"`
+	Creator     string    `json:"creator,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ContinueUrl follows the short without stopping at the preview again.
+	ContinueUrl string `json:"continue_url"`
 }
+
 type ShortResponse struct {
-	Id          types.ShortId `json:"id"`
-	OriginalUrl string        `json:"original_url"`
-	ShortUrl    string        `json:"short_url"`
+	Id             types.ShortId          `json:"id"`
+	OriginalUrl    string                 `json:"original_url"`
+	ShortUrl       string                 `json:"short_url"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	MaxClicks      *int                   `json:"max_clicks,omitempty"`
+	ClickCount     int                    `json:"click_count"`
+	Protected      bool                   `json:"protected"`
+	Enabled        bool                   `json:"enabled"`
+	RedirectStatus int                    `json:"redirect_status"`
+	ForwardQuery   bool                   `json:"forward_query"`
+	AlwaysPreview  bool                   `json:"always_preview"`
+	DomainID       types.DomainId         `json:"domain_id,omitempty"`
+	FolderID       *types.FolderId        `json:"folder_id,omitempty"`
+	CampaignID     *types.CampaignId      `json:"campaign_id,omitempty"`
+	Tags           []string               `json:"tags"`
+	ActivateAt     *time.Time             `json:"activate_at,omitempty"`
+	ComingSoonUrl  string                 `json:"coming_soon_url,omitempty"`
+	Destinations   []DestinationResponse  `json:"destinations,omitempty"`
+	Rules          []RedirectRuleResponse `json:"rules,omitempty"`
+	Variants       []VariantResponse      `json:"variants,omitempty"`
+	// Metadata describes the destination page, nil until it was fetched.
+	Metadata    *LinkMetadata `json:"metadata,omitempty"`
+	FallbackUrl string        `json:"fallback_url,omitempty"`
+	// Health is the outcome of the last checks of OriginalUrl, nil until it
+	// was first checked.
+	Health *LinkHealth `json:"health,omitempty"`
+	// DeletedAt is when the short was moved to the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (r ShortenRequest) hasOptions() bool {
+	return r.ExpiresAt != nil || r.MaxClicks != nil || r.Password != nil ||
+		(r.RedirectStatus != nil && *r.RedirectStatus != DefaultRedirectStatus) || r.ForwardQuery || r.AlwaysPreview ||
+		len(r.Tags) > 0 || r.FolderID != nil || r.ActivateAt != nil || len(r.Destinations) > 0 ||
+		len(r.Rules) > 0 || len(r.Variants) > 0 || r.FallbackUrl != nil || r.CampaignID != nil
+}
+
+func NewShortResponse(short ShortModel) ShortResponse {
+	return ShortResponse{
+		Id:             short.ID,
+		OriginalUrl:    short.OriginalUrl,
+		ShortUrl:       short.ShortUrl,
+		ExpiresAt:      short.ExpiresAt,
+		MaxClicks:      short.MaxClicks,
+		ClickCount:     short.ClickCount,
+		Protected:      short.IsProtected(),
+		Enabled:        !short.Disabled,
+		RedirectStatus: short.RedirectStatus,
+		ForwardQuery:   short.ForwardQuery,
+		AlwaysPreview:  short.AlwaysPreview,
+		DomainID:       short.DomainID,
+		FolderID:       short.FolderID,
+		CampaignID:     short.CampaignID,
+		Tags:           short.TagNames(),
+		ActivateAt:     short.ActivateAt,
+		ComingSoonUrl:  short.ComingSoonUrl,
+		Destinations:   newDestinationResponses(short.Destinations),
+		Rules:          newRedirectRuleResponses(short.Rules),
+		Variants:       newVariantResponses(short.Variants),
+		Metadata:       newMetadataResponse(short.Metadata),
+		FallbackUrl:    short.FallbackUrl,
+		Health:         newHealthResponse(short.Health),
+		DeletedAt:      deletedAt(short),
+	}
+}
+
+func deletedAt(short ShortModel) *time.Time {
+	if !short.DeletedAt.Valid {
+		return nil
+	}
+	return &short.DeletedAt.Time
+}
+
+func newMetadataResponse(meta LinkMetadata) *LinkMetadata {
+	if meta.FetchedAt == nil {
+		return nil
+	}
+	return &meta
+}
+
+func newHealthResponse(health LinkHealth) *LinkHealth {
+	if health.CheckedAt == nil {
+		return nil
+	}
+	return &health
+}
+
+func newVariantResponses(variants []VariantModel) []VariantResponse {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	responses := make([]VariantResponse, 0, len(variants))
+	for _, variant := range variants {
+		responses = append(responses, VariantResponse{
+			Id:     variant.ID,
+			Name:   variant.Name,
+			Url:    variant.Url,
+			Weight: variant.Weight,
+		})
+	}
+	return responses
+}
+
+func newRedirectRuleResponses(rules []RedirectRuleModel) []RedirectRuleResponse {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	responses := make([]RedirectRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		responses = append(responses, RedirectRuleResponse{
+			OS:       rule.OS,
+			Device:   rule.Device,
+			Language: rule.Language,
+			Country:  rule.Country,
+			Url:      rule.Url,
+		})
+	}
+	return responses
+}
+
+func newDestinationResponses(destinations []DestinationModel) []DestinationResponse {
+	if len(destinations) == 0 {
+		return nil
+	}
+
+	responses := make([]DestinationResponse, 0, len(destinations))
+	for _, destination := range destinations {
+		responses = append(responses, DestinationResponse{
+			Url:      destination.Url,
+			StartsAt: destination.StartsAt,
+			EndsAt:   destination.EndsAt,
+		})
+	}
+	return responses
 }
 
 type SearchRequest struct {
-	OriginalUrl *string       `json:"original_url,omitempty"`
-	UserId      *types.UserId `json:"user_id,omitempty"`
-	ShortUrl    *string       `json:"short_url,omitempty"`
+	OriginalUrl *string         `json:"original_url,omitempty"`
+	UserId      *types.UserId   `json:"user_id,omitempty"`
+	ShortUrl    *string         `json:"short_url,omitempty"`
+	DomainID    *types.DomainId `json:"domain_id,omitempty"`
+	Tag         *string         `json:"tag,omitempty"`
+	FolderID    *types.FolderId `json:"folder_id,omitempty"`
+	// CreatedFrom and CreatedTo bound the creation time, both inclusive.
+	CreatedFrom *time.Time `json:"created_from,omitempty"`
+	CreatedTo   *time.Time `json:"created_to,omitempty"`
+	// Query matches a case-insensitive substring of the original or short URL.
+	Query *string `json:"query,omitempty" validate:"omitempty,min=1,max=256"`
+	// Sort orders the results by a field, descending when prefixed with "-".
+	Sort *string `json:"sort,omitempty" validate:"omitempty,oneof=created_at -created_at click_count -click_count short_url -short_url original_url -original_url"`
+}
+
+type FolderCreateRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=64"`
+}
+
+type FolderResponse struct {
+	Id        types.FolderId `json:"id"`
+	Name      string         `json:"name"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func NewFolderResponse(folder FolderModel) FolderResponse {
+	return FolderResponse{Id: folder.ID, Name: folder.Name, CreatedAt: folder.CreatedAt}
+}
+
+type CampaignCreateRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=64"`
+	UtmSource   string `json:"utm_source" validate:"max=100"`
+	UtmMedium   string `json:"utm_medium" validate:"max=100"`
+	UtmCampaign string `json:"utm_campaign" validate:"max=100"`
+}
+
+type CampaignResponse struct {
+	Id          types.CampaignId `json:"id"`
+	Name        string           `json:"name"`
+	UtmSource   string           `json:"utm_source,omitempty"`
+	UtmMedium   string           `json:"utm_medium,omitempty"`
+	UtmCampaign string           `json:"utm_campaign,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+func NewCampaignResponse(campaign CampaignModel) CampaignResponse {
+	return CampaignResponse{
+		Id:          campaign.ID,
+		Name:        campaign.Name,
+		UtmSource:   campaign.UtmSource,
+		UtmMedium:   campaign.UtmMedium,
+		UtmCampaign: campaign.UtmCampaign,
+		CreatedAt:   campaign.CreatedAt,
+	}
 }
+
+// PaginatedResponse is one page of shorts. Total and TotalPages are only set
+// when the exact count was requested.
 type PaginatedResponse struct {
 	Page       int             `json:"page"`
 	PageSize   int             `json:"pageSize"`
-	Total      int             `json:"total"`
-	TotalPages int             `json:"totalPages"`
+	Total      *int            `json:"total,omitempty"`
+	TotalPages *int            `json:"totalPages,omitempty"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 	Data       []ShortResponse `json:"data"`
 }
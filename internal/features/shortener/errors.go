@@ -8,4 +8,89 @@ var (
 	ErrShortenFailed         = errors.New("Failed to shorten URL")
 	ErrInvalidShortenRequest = errors.New("Invalid shorten request")
 	ErrShortDeleteFail       = errors.New("Failed to delete short URL")
+	ErrInvalidCustomShortUrl = errors.New("Invalid custom short URL")
+	ErrReservedShortUrl      = errors.New("Custom short URL is reserved")
+	ErrProfaneShortUrl       = errors.New("Custom short URL contains a blocked word")
+	ErrTrademarkShortUrl     = errors.New("Custom short URL contains a protected name")
+	ErrShortUrlTooShort      = errors.New("Custom short URL is too short")
+	ErrShortUrlCoolingDown   = errors.New("Short URL was released recently")
+	ErrShortUrlTaken         = errors.New("Short URL is already taken")
+	ErrShortCodeExhausted    = errors.New("Could not generate a unique short code")
+	ErrInvalidExpiry         = errors.New("Expiry time must be in the future")
+	ErrShortExpired          = errors.New("Short has expired")
+	ErrShortClickLimit       = errors.New("Short has reached its click limit")
+	ErrInvalidShortPassword  = errors.New("Invalid password")
+	ErrTooManyUnlockAttempts = errors.New("Too many failed password attempts")
+	ErrShortDisabled         = errors.New("Short is disabled")
+	ErrShortUpdateFail       = errors.New("Failed to update short URL")
+	ErrInvalidUpdateRequest  = errors.New("Invalid update request")
+	ErrShortNotOwned         = errors.New("Short belongs to another user")
+	ErrBulkTooLarge          = errors.New("Too many items in bulk request")
+	ErrBulkRolledBack        = errors.New("Rolled back because another item failed")
+	ErrInvalidCSV            = errors.New("Invalid CSV file")
+	ErrInvalidCSVRow         = errors.New("Invalid CSV row")
+	ErrInvalidQRRequest      = errors.New("Invalid QR code options")
+	ErrDomainUnavailable     = errors.New("Domain is not available for this user")
+	ErrFolderNotFound        = errors.New("Folder not found")
+	ErrFolderNameTaken       = errors.New("Folder name is already in use")
+	ErrCampaignNotFound      = errors.New("Campaign not found")
+	ErrCampaignNameTaken     = errors.New("Campaign name is already in use")
+	ErrInvalidSchedule       = errors.New("Invalid schedule")
+	ErrShortNotActive        = errors.New("Short is not active yet")
+	ErrInvalidRedirectRule   = errors.New("Invalid redirect rule")
+	ErrInvalidVariants       = errors.New("Invalid A/B variants")
+	ErrUnsafeURL             = errors.New("URL is not allowed")
+	ErrShortBlocked          = errors.New("Short has been blocked")
+	ErrMetadataFetchFail     = errors.New("Failed to fetch link metadata")
+	ErrHealthCheckFail       = errors.New("Failed to check link health")
+	ErrVersionNotFound       = errors.New("Version not found")
 )
+
+// ErrorCode returns a stable, machine readable code for errors returned while
+// shortening, for clients that cannot rely on the error messages.
+func ErrorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInvalidShortenRequest):
+		return "invalid_request"
+	case errors.Is(err, ErrInvalidCSVRow):
+		return "invalid_row"
+	case errors.Is(err, ErrInvalidCustomShortUrl):
+		return "invalid_short_url"
+	case errors.Is(err, ErrReservedShortUrl):
+		return "reserved_short_url"
+	case errors.Is(err, ErrProfaneShortUrl):
+		return "profane_short_url"
+	case errors.Is(err, ErrTrademarkShortUrl):
+		return "trademark_short_url"
+	case errors.Is(err, ErrShortUrlTooShort):
+		return "short_url_too_short"
+	case errors.Is(err, ErrShortUrlCoolingDown):
+		return "short_url_cooling_down"
+	case errors.Is(err, ErrShortUrlTaken):
+		return "short_url_taken"
+	case errors.Is(err, ErrInvalidExpiry):
+		return "invalid_expiry"
+	case errors.Is(err, ErrInvalidSchedule):
+		return "invalid_schedule"
+	case errors.Is(err, ErrInvalidRedirectRule):
+		return "invalid_rule"
+	case errors.Is(err, ErrInvalidVariants):
+		return "invalid_variants"
+	case errors.Is(err, ErrUnsafeURL):
+		return "unsafe_url"
+	case errors.Is(err, ErrDomainUnavailable):
+		return "domain_unavailable"
+	case errors.Is(err, ErrFolderNotFound):
+		return "folder_not_found"
+	case errors.Is(err, ErrCampaignNotFound):
+		return "campaign_not_found"
+	case errors.Is(err, ErrShortCodeExhausted):
+		return "code_exhausted"
+	case errors.Is(err, ErrBulkRolledBack):
+		return "rolled_back"
+	default:
+		return "shorten_failed"
+	}
+}
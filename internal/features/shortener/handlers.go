@@ -1,16 +1,22 @@
 package shortener
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Kalmera74/Shorty/internal/middleware"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/Kalmera74/Shorty/pkg/messaging"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
@@ -25,29 +31,29 @@ func NewShortHandler(service IShortService, messaging messaging.IMessaging) *Sho
 	return &ShortHandler{service: service, messaging: messaging}
 }
 
+// GetAll pages through the shorts of all users. Pass next_cursor back as
+// cursor to fetch the following page.
+//
 // GetAll godoc
 // @Summary Get all shortened URLs (with pagination)
 // @Description Retrieve shortened URLs with pagination
 // @Tags shorts
 // @Produce json
-// @Param page query int false "Page number" default(1)
+// @Param page query int false "Page number, ignored when cursor is set" default(1)
 // @Param pageSize query int false "Number of items per page" default(10)
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Param count query bool false "Include the exact total, on by default only without cursor"
 // @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /api/v1/shorts [get]
 func (h *ShortHandler) GetAll(c *fiber.Ctx) error {
-	// Parse query params
-	page := c.QueryInt("page", 1)        // default = 1
-	pageSize := c.QueryInt("pageSize", 10) // default = 10
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10 // prevent abuse
+	params, page, err := pagination.FromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	// Call service with pagination
-	shortModels, total, err := h.service.GetAll(c.Context(), page, pageSize)
+	shortModels, result, err := h.service.GetAll(c.Context(), params)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -55,23 +61,23 @@ func (h *ShortHandler) GetAll(c *fiber.Ctx) error {
 	// Map to response DTOs
 	shortResponses := []ShortResponse{}
 	for _, shortModel := range shortModels {
-		shortResponses = append(shortResponses, ShortResponse{
-			Id:          shortModel.ID,
-			OriginalUrl: shortModel.OriginalUrl,
-			ShortUrl:    shortModel.ShortUrl,
-		})
+		shortResponses = append(shortResponses, NewShortResponse(shortModel))
 	}
 
 	// Return paginated response
 	return c.JSON(PaginatedResponse{
 		Page:       page,
-		PageSize:   pageSize,
-		Total:      total,
-		TotalPages: (total + pageSize - 1) / pageSize,
+		PageSize:   params.Limit,
+		Total:      result.Total,
+		TotalPages: result.TotalPages(params.Limit),
+		NextCursor: result.NextCursor,
 		Data:       shortResponses,
 	})
 }
 
+// Shorten creates a short for the authenticated user. Only admins may set
+// user_id.
+//
 // Shorten godoc
 // @Summary Create a new shortened URL
 // @Description Shorten a given URL
@@ -81,25 +87,136 @@ func (h *ShortHandler) GetAll(c *fiber.Ctx) error {
 // @Param request body ShortenRequest true "ShortenRequest"
 // @Success 200 {object} ShortResponse
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/shorts [post]
 func (h *ShortHandler) Shorten(c *fiber.Ctx) error {
+	userID, role, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	var req ShortenRequest
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
 	}
+	if role != "admin" || req.UserID == 0 {
+		req.UserID = userID
+	}
 	if err := validate.Struct(req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	short, err := h.service.ShortenURL(c.Context(), req)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(ShortResponse{
-		Id:          short.ID,
-		OriginalUrl: short.OriginalUrl,
-		ShortUrl:    short.ShortUrl,
-	})
+	return c.JSON(NewShortResponse(short))
+}
+
+// BulkShorten creates several shorts in one request. With atomic set either
+// all items are created or none are. Only admins may set user_id.
+//
+// BulkShorten godoc
+// @Summary Create several shortened URLs at once
+// @Description Shortens every item and reports a result per item
+// @Tags shorts
+// @Accept json
+// @Produce json
+// @Param request body BulkShortenRequest true "BulkShortenRequest"
+// @Success 200 {object} BulkShortenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/shorts/bulk [post]
+func (h *ShortHandler) BulkShorten(c *fiber.Ctx) error {
+	userID, role, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req BulkShortenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	for i := range req.Items {
+		if role != "admin" || req.Items[i].UserID == 0 {
+			req.Items[i].UserID = userID
+		}
+	}
+
+	results, err := h.service.BulkShorten(c.Context(), req.Items, req.Atomic)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrBulkTooLarge):
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, ErrInvalidShortenRequest):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	response := BulkShortenResponse{Results: make([]BulkShortenItemResult, 0, len(results))}
+	for i, result := range results {
+		item := BulkShortenItemResult{Index: i}
+		if result.Err != nil {
+			item.Code = ErrorCode(result.Err)
+			item.Error = result.Err.Error()
+			response.Failed++
+		} else {
+			short := NewShortResponse(result.Short)
+			item.Short = &short
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, item)
+	}
+	return c.JSON(response)
+}
+
+// Update changes a short as an admin. The change is added to the short's
+// history.
+//
+// Update godoc
+// @Summary Update a shortened URL
+// @Description Retarget, rename, enable or disable a short
+// @Tags shorts
+// @Accept json
+// @Produce json
+// @Param id path int true "Short ID"
+// @Param request body UpdateShortRequest true "Fields to change"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/shorts/{id} [patch]
+func (h *ShortHandler) Update(c *fiber.Ctx) error {
+	editor, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req UpdateShortRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	short, err := h.service.UpdateShort(c.Context(), editor, types.ShortId(id), req)
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(short))
 }
 
 // GetById godoc
@@ -121,11 +238,171 @@ func (h *ShortHandler) GetById(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
 	}
-	return c.JSON(ShortResponse{
-		Id:          shortModel.ID,
-		OriginalUrl: shortModel.OriginalUrl,
-		ShortUrl:    shortModel.ShortUrl,
-	})
+	return c.JSON(NewShortResponse(shortModel))
+}
+
+// GetQRCode renders a short as a QR code. Users may only render their own
+// shorts.
+//
+// GetQRCode godoc
+// @Summary Get a QR code for a short
+// @Description Renders the full short URL as a QR code
+// @Tags shorts
+// @Produce png
+// @Produce image/svg+xml
+// @Param id path int true "Short ID"
+// @Param format query string false "Image format" Enums(png, svg) default(png)
+// @Param size query int false "Width and height in pixels" default(256)
+// @Param ec query string false "Error correction level" Enums(L, M, Q, H) default(M)
+// @Param fg query string false "Foreground colour as rrggbb" default(000000)
+// @Param bg query string false "Background colour as rrggbb" default(ffffff)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/shorts/{id}/qr [get]
+func (h *ShortHandler) GetQRCode(c *fiber.Ctx) error {
+	userID, role, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req QRCodeRequest
+	if err := c.QueryParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var shortModel ShortModel
+	if role == "admin" {
+		shortModel, err = h.service.GetById(c.Context(), types.ShortId(id))
+	} else {
+		shortModel, err = h.service.GetByIdForUser(c.Context(), userID, types.ShortId(id))
+	}
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	image, err := h.service.QRCode(c.Context(), h.service.ShortLink(c.Context(), shortModel, baseURL(c)), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidQRRequest) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	if strings.EqualFold(req.Format, "svg") {
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+	}
+	c.Set(fiber.HeaderCacheControl, "private, max-age=3600")
+	return c.Send(image)
+}
+
+// GetHistory lists who changed a short and when, with the short before and
+// after each change. Users may only see the history of their own shorts.
+//
+// GetHistory godoc
+// @Summary List the changes of a short
+// @Description Lists every change of the short, newest first
+// @Tags shorts
+// @Produce json
+// @Param id path int true "Short ID"
+// @Success 200 {array} ShortHistoryResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/shorts/{id}/history [get]
+func (h *ShortHandler) GetHistory(c *fiber.Ctx) error {
+	userID, role, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if role != "admin" {
+		if _, err := h.service.GetByIdForUser(c.Context(), userID, types.ShortId(id)); err != nil {
+			return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	entries, err := h.service.GetHistory(c.Context(), types.ShortId(id))
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]ShortHistoryResponse, 0, len(entries))
+	for _, entry := range entries {
+		responses = append(responses, NewShortHistoryResponse(entry))
+	}
+	return c.JSON(responses)
+}
+
+// Rollback restores a short as it was after the given version, or as it
+// was before its first change for version 0. The rollback is added to the
+// history as a change of its own. Users may only roll back their own shorts.
+//
+// Rollback godoc
+// @Summary Restore an earlier version of a short
+// @Description Restores the short as it was after the given version
+// @Tags shorts
+// @Produce json
+// @Param id path int true "Short ID"
+// @Param version path int true "Version to restore"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/shorts/{id}/history/{version}/rollback [post]
+func (h *ShortHandler) Rollback(c *fiber.Ctx) error {
+	userID, role, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if role != "admin" {
+		if _, err := h.service.GetByIdForUser(c.Context(), userID, types.ShortId(id)); err != nil {
+			return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	short, err := h.service.Rollback(c.Context(), userID, types.ShortId(id), version)
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(short))
+}
+
+// baseURL returns the public address of the shared host, BASE_URL when it
+// is set and the address of the current request otherwise.
+func baseURL(c *fiber.Ctx) string {
+	if base := os.Getenv("BASE_URL"); base != "" {
+		return base
+	}
+	return c.BaseURL()
 }
 
 // GetByShortUrl godoc
@@ -133,20 +410,18 @@ func (h *ShortHandler) GetById(c *fiber.Ctx) error {
 // @Tags shorts
 // @Produce json
 // @Param url path string true "Short URL"
+// @Param domain_id query int false "Custom domain ID, 0 for the shared host"
 // @Success 200 {object} ShortResponse
 // @Failure 404 {object} map[string]string
 // @Router /api/v1/shorts/short/{url} [get]
 func (h *ShortHandler) GetByShortUrl(c *fiber.Ctx) error {
 	shortUrl := c.Params("url")
-	shortModel, err := h.service.GetByShortUrl(c.Context(), shortUrl)
+	domainID := types.DomainId(c.QueryInt("domain_id", 0))
+	shortModel, err := h.service.GetByShortUrl(c.Context(), domainID, shortUrl)
 	if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Short not found"})
 	}
-	return c.JSON(ShortResponse{
-		Id:          shortModel.ID,
-		OriginalUrl: shortModel.OriginalUrl,
-		ShortUrl:    shortModel.ShortUrl,
-	})
+	return c.JSON(NewShortResponse(shortModel))
 }
 
 // Search godoc
@@ -180,33 +455,102 @@ func (h *ShortHandler) Search(c *fiber.Ctx) error {
 
 	responses := make([]ShortResponse, 0, len(shortModels))
 	for _, sm := range shortModels {
-		responses = append(responses, ShortResponse{
-			Id:          sm.ID,
-			OriginalUrl: sm.OriginalUrl,
-			ShortUrl:    sm.ShortUrl,
-		})
+		responses = append(responses, NewShortResponse(sm))
 	}
 
 	return c.JSON(responses)
 }
 
+// RedirectToOriginalUrl resolves the short URL within the custom domain named
+// by the Host header, or the shared host. It redirects to the URL of the first
+// redirect rule matching the visitor's User-Agent, Accept-Language and GeoIP
+// country, then the scheduled destination for the current time, then the
+// visitor's A/B variant, and finally the original URL, which is replaced by
+// the short's fallback URL while the health worker finds it broken. New
+// visitors of a short with variants are assigned one by weight and pinned to
+// it with a cookie.
+//
+// Password-protected shorts answer with an HTML unlock form instead. A +
+// after the short URL, or the short's always preview flag, shows a preview
+// page with the destination, creator and creation date and a continue button
+// instead of redirecting; clients that prefer JSON get the preview as JSON.
+// Shorts that are not active yet redirect to their coming soon URL, or answer
+// 404 without one.
+//
 // RedirectToOriginalUrl godoc
 // @Summary Redirect to the original URL
+// @Description Redirects to the destination of the short URL
 // @Tags shorts
 // @Produce json
-// @Param url path string true "Short URL"
-// @Success 301 {string} string "Redirects to the original URL"
-// @Failure 404 {object} map[string]string
+// @Produce html
+// @Param url path string true "Short URL, with a trailing + for a preview"
+// @Param continue query string false "Set by the preview page to redirect past the always preview flag"
+// @Success 302 {string} string "Redirects to the original URL with the short's redirect status"
+// @Success 200 {object} PreviewResponse "Preview page, or unlock form for password-protected shorts"
+// @Failure 404 {object} map[string]string "Short not found, disabled or not active yet"
+// @Failure 410 {object} map[string]string "Short expired or click limit reached"
 // @Failure 500 {object} map[string]string
 // @Router /{url} [get]
 func (h *ShortHandler) RedirectToOriginalUrl(c *fiber.Ctx) error {
-	short := c.Params("url")
-	shortModel, err := h.service.GetByShortUrl(c.Context(), short)
+	short, suffixed := strings.CutSuffix(c.Params("url"), previewSuffix)
+	shortModel, err := h.service.Resolve(c.Context(), c.Hostname(), short)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Short not found"})
+		return redirectError(c, err)
+	}
+
+	// The unlock form already stops the visitor, and a preview would give
+	// the destination away before the password is entered.
+	if shortModel.IsProtected() {
+		return renderUnlockPage(c, fiber.StatusOK, "")
+	}
+
+	if wantsPreview(c, shortModel, suffixed) {
+		return h.preview(c, shortModel)
+	}
+
+	return h.redirect(c, shortModel)
+}
+
+// UnlockShort godoc
+// @Summary Unlock a password-protected short
+// @Description Checks the submitted password and redirects to the original URL
+// @Tags shorts
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param url path string true "Short URL, with the trailing + it was opened with"
+// @Param password formData string true "Link password"
+// @Success 302 {string} string "Redirects to the original URL with the short's redirect status"
+// @Failure 401 {string} string "Unlock form with an error message"
+// @Failure 404 {object} map[string]string
+// @Failure 410 {object} map[string]string "Short expired or click limit reached"
+// @Failure 429 {string} string "Unlock form, too many failed attempts"
+// @Router /{url} [post]
+func (h *ShortHandler) UnlockShort(c *fiber.Ctx) error {
+	short, _ := strings.CutSuffix(c.Params("url"), previewSuffix)
+	shortModel, err := h.service.Resolve(c.Context(), c.Hostname(), short)
+	if err != nil {
+		return redirectError(c, err)
+	}
+
+	if err := h.service.Unlock(c.Context(), shortModel, c.FormValue("password")); err != nil {
+		switch {
+		case errors.Is(err, ErrTooManyUnlockAttempts):
+			return renderUnlockPage(c, fiber.StatusTooManyRequests, "Too many failed attempts, please try again later.")
+		case errors.Is(err, ErrInvalidShortPassword):
+			return renderUnlockPage(c, fiber.StatusUnauthorized, "Incorrect password.")
+		default:
+			return redirectError(c, err)
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return h.redirect(c, shortModel)
+}
+
+// redirect counts the visit, publishes the click event and sends the client
+// on to the original URL.
+func (h *ShortHandler) redirect(c *fiber.Ctx, shortModel ShortModel) error {
+	if err := h.service.ConsumeClick(c.Context(), shortModel); err != nil {
+		return redirectError(c, err)
 	}
 
 	clickQue := os.Getenv("CLICK_QUEUE")
@@ -219,6 +563,13 @@ func (h *ShortHandler) RedirectToOriginalUrl(c *fiber.Ctx) error {
 	shortID := shortModel.ID
 	timeStamp := time.Now()
 
+	if len(shortModel.Rules) > 0 {
+		c.Vary(fiber.HeaderUserAgent, fiber.HeaderAcceptLanguage)
+	}
+
+	destination, variantID := shortModel.Target(h.visitor(c, shortModel), timeStamp)
+	pinVariant(c, shortID, variantID)
+
 	go func() {
 		event := map[string]any{
 			"short_id":   shortID,
@@ -226,11 +577,66 @@ func (h *ShortHandler) RedirectToOriginalUrl(c *fiber.Ctx) error {
 			"user_agent": ua,
 			"time_stamp": timeStamp,
 		}
+		if variantID != 0 {
+			event["variant_id"] = variantID
+		}
 		payload, _ := json.Marshal(event)
 		_ = h.messaging.Publish(clickQue, payload)
 	}()
 
-	return c.Redirect(shortModel.OriginalUrl, fiber.StatusMovedPermanently)
+	if shortModel.ForwardQuery {
+		destination = mergeQuery(destination, forwardedQuery(c))
+	}
+
+	return c.Redirect(destination, redirectStatus(shortModel))
+}
+
+// shortErrorStatus maps errors from creating or changing a short to a status.
+func shortErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidCustomShortUrl),
+		errors.Is(err, ErrReservedShortUrl),
+		errors.Is(err, ErrProfaneShortUrl),
+		errors.Is(err, ErrTrademarkShortUrl),
+		errors.Is(err, ErrShortUrlTooShort),
+		errors.Is(err, ErrInvalidExpiry),
+		errors.Is(err, ErrInvalidSchedule),
+		errors.Is(err, ErrInvalidRedirectRule),
+		errors.Is(err, ErrInvalidVariants),
+		errors.Is(err, ErrUnsafeURL),
+		errors.Is(err, ErrInvalidUpdateRequest):
+		return fiber.StatusBadRequest
+	case errors.Is(err, ErrShortNotFound), errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, ErrFolderNotFound), errors.Is(err, ErrCampaignNotFound), errors.Is(err, ErrVersionNotFound):
+		return fiber.StatusNotFound
+	case errors.Is(err, ErrShortNotOwned), errors.Is(err, ErrDomainUnavailable):
+		return fiber.StatusForbidden
+	case errors.Is(err, ErrShortUrlTaken), errors.Is(err, ErrShortUrlCoolingDown),
+		errors.Is(err, ErrFolderNameTaken), errors.Is(err, ErrCampaignNameTaken):
+		return fiber.StatusConflict
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+func redirectError(c *fiber.Ctx, err error) error {
+	var notActive *NotActiveError
+	if errors.As(err, &notActive) && notActive.ComingSoonUrl != "" {
+		return c.Redirect(notActive.ComingSoonUrl, fiber.StatusFound)
+	}
+
+	switch {
+	case errors.Is(err, ErrShortNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Short not found"})
+	case errors.Is(err, ErrShortDisabled), errors.Is(err, ErrShortNotActive):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrShortExpired), errors.Is(err, ErrShortClickLimit):
+		return c.Status(fiber.StatusGone).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrShortBlocked):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 }
 
 // GetAllByUser godoc
@@ -254,17 +660,17 @@ func (h *ShortHandler) GetAllByUser(c *fiber.Ctx) error {
 	}
 	shortResponses := []ShortResponse{}
 	for _, shortModel := range shortModels {
-		shortResponses = append(shortResponses, ShortResponse{
-			Id:          shortModel.ID,
-			OriginalUrl: shortModel.OriginalUrl,
-			ShortUrl:    shortModel.ShortUrl,
-		})
+		shortResponses = append(shortResponses, NewShortResponse(shortModel))
 	}
 	return c.JSON(shortResponses)
 }
 
+// Delete moves a short to the trash. Its short URL stays reserved until the
+// short is purged.
+//
 // Delete godoc
 // @Summary Delete a shortened URL
+// @Description Moves the short to the trash
 // @Tags shorts
 // @Produce json
 // @Param id path int true "Short ID"
@@ -280,10 +686,630 @@ func (h *ShortHandler) Delete(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 	if err = h.service.DeleteURL(c.Context(), types.ShortId(id)); err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetTrash lists the trashed shorts of all users. They are purged for good
+// once the trash retention period has passed.
+//
+// GetTrash godoc
+// @Summary List the trashed shorts of all users
+// @Description Lists the shorts in the trash
+// @Tags shorts
+// @Produce json
+// @Success 200 {array} ShortResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/shorts/trash [get]
+func (h *ShortHandler) GetTrash(c *fiber.Ctx) error {
+	return h.trash(c, nil)
+}
+
+// Restore godoc
+// @Summary Restore a trashed short
+// @Tags shorts
+// @Produce json
+// @Param id path int true "Short ID"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/shorts/{id}/restore [post]
+func (h *ShortHandler) Restore(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	short, err := h.service.RestoreURL(c.Context(), types.ShortId(id))
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(short))
+}
+
+func (h *ShortHandler) trash(c *fiber.Ctx, userID *types.UserId) error {
+	shortModels, err := h.service.GetTrash(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shortResponses := make([]ShortResponse, 0, len(shortModels))
+	for _, shortModel := range shortModels {
+		shortResponses = append(shortResponses, NewShortResponse(shortModel))
+	}
+	return c.JSON(shortResponses)
+}
+
+// GetMine godoc
+// @Summary List the authenticated user's shorts
+// @Tags me
+// @Produce json
+// @Success 200 {array} ShortResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/shorts [get]
+func (h *ShortHandler) GetMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shortModels, err := h.service.GetAllByUser(c.Context(), userID)
+	if err != nil && !errors.Is(err, ErrShortNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shortResponses := make([]ShortResponse, 0, len(shortModels))
+	for _, shortModel := range shortModels {
+		shortResponses = append(shortResponses, NewShortResponse(shortModel))
+	}
+	return c.JSON(shortResponses)
+}
+
+// GetMineById godoc
+// @Summary Get one of the authenticated user's shorts
+// @Tags me
+// @Produce json
+// @Param id path int true "Short ID"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/shorts/{id} [get]
+func (h *ShortHandler) GetMineById(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shortModel, err := h.service.GetByIdForUser(c.Context(), userID, types.ShortId(id))
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(shortModel))
+}
+
+// UpdateMine godoc
+// @Summary Update one of the authenticated user's shorts
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param id path int true "Short ID"
+// @Param request body UpdateShortRequest true "Fields to change"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/me/shorts/{id} [patch]
+func (h *ShortHandler) UpdateMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req UpdateShortRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	shortModel, err := h.service.UpdateShortForUser(c.Context(), userID, types.ShortId(id), req)
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(shortModel))
+}
+
+// DeleteMine moves a short of the authenticated user to the trash, from
+// where it can be restored until it is purged.
+//
+// DeleteMine godoc
+// @Summary Delete one of the authenticated user's shorts
+// @Description Moves the short to the trash
+// @Tags me
+// @Param id path int true "Short ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/shorts/{id} [delete]
+func (h *ShortHandler) DeleteMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.service.DeleteURLForUser(c.Context(), userID, types.ShortId(id)); err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetMyTrash lists the authenticated user's trashed shorts. They are purged
+// for good once the trash retention period has passed.
+//
+// GetMyTrash godoc
+// @Summary List the authenticated user's trashed shorts
+// @Description Lists the shorts in the trash
+// @Tags me
+// @Produce json
+// @Success 200 {array} ShortResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/shorts/trash [get]
+func (h *ShortHandler) GetMyTrash(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	return h.trash(c, &userID)
+}
+
+// RestoreMine godoc
+// @Summary Restore one of the authenticated user's trashed shorts
+// @Tags me
+// @Produce json
+// @Param id path int true "Short ID"
+// @Success 200 {object} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/shorts/{id}/restore [post]
+func (h *ShortHandler) RestoreMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	short, err := h.service.RestoreURLForUser(c.Context(), userID, types.ShortId(id))
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewShortResponse(short))
+}
+
+// ImportMine creates shorts from an uploaded CSV. The header must name an
+// original_url column and may name custom_short_url, expires_at and tags
+// columns. Failed rows are reported by line number.
+//
+// ImportMine godoc
+// @Summary Import shorts from a CSV file
+// @Description Creates a short for every row of the uploaded CSV
+// @Tags me
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV file"
+// @Success 200 {object} ImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/shorts/import [post]
+func (h *ShortHandler) ImportMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "missing CSV file"})
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	defer file.Close()
+
+	result, err := h.service.ImportCSV(c.Context(), userID, file)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCSV) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
+	return c.JSON(result)
+}
+
+// ExportMine godoc
+// @Summary Export the authenticated user's shorts as CSV
+// @Tags me
+// @Produce text/csv
+// @Success 200 {string} string "CSV file"
+// @Failure 401 {object} map[string]string
+// @Router /api/v1/me/shorts/export [get]
+func (h *ShortHandler) ExportMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	return h.streamCSV(c, &userID)
+}
+
+// Export godoc
+// @Summary Export shorts as CSV
+// @Description Exports every short, or only those of user_id when it is given
+// @Tags shorts
+// @Produce text/csv
+// @Param user_id query int false "Only export shorts of this user"
+// @Success 200 {string} string "CSV file"
+// @Failure 400 {object} map[string]string
+// @Router /api/v1/shorts/export [get]
+func (h *ShortHandler) Export(c *fiber.Ctx) error {
+	var userID *types.UserId
+	if param := c.Query("user_id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user ID"})
+		}
+		uid := types.UserId(id)
+		userID = &uid
+	}
+	return h.streamCSV(c, userID)
+}
+
+// streamCSV writes the export while it is read from the database. The status
+// is sent before the first row, so a failure part way through can only cut
+// the file short; it is logged instead. The export stops as soon as the
+// client cannot be written to any more.
+func (h *ShortHandler) streamCSV(c *fiber.Ctx, userID *types.UserId) error {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="shorts.csv"`)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		err := h.service.ExportCSV(ctx, userID, &clientWriter{w: w, cancel: cancel})
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			log.Error().Err(err).Msg("CSV export failed")
+		}
+	})
+	return nil
+}
+
+// clientWriter sends every write on to the client right away, so a client
+// that went away is noticed at the next write. The first failure cancels the
+// export and every later write fails with it.
+type clientWriter struct {
+	w      *bufio.Writer
+	cancel context.CancelFunc
+	err    error
+}
+
+func (cw *clientWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	if err == nil {
+		err = cw.w.Flush()
+	}
+	if err != nil {
+		cw.err = err
+		cw.cancel()
+	}
+	return n, err
+}
+
+// SearchMine filters the authenticated user's shorts by tag, folder,
+// creation date range and URL substring, with optional sorting. A user_id in
+// the body is ignored.
+//
+// SearchMine godoc
+// @Summary Search the authenticated user's shorts
+// @Description Filters the user's shorts and sorts them
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body SearchRequest true "Search criteria"
+// @Success 200 {array} ShortResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/shorts/search [post]
+func (h *ShortHandler) SearchMine(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req SearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	req.UserId = &userID
+
+	shortModels, err := h.service.Search(c.Context(), req)
+	if err != nil && !errors.Is(err, ErrShortNotFound) {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]ShortResponse, 0, len(shortModels))
+	for _, shortModel := range shortModels {
+		responses = append(responses, NewShortResponse(shortModel))
+	}
+	return c.JSON(responses)
+}
+
+// GetTags godoc
+// @Summary List the authenticated user's tags
+// @Tags me
+// @Produce json
+// @Success 200 {array} string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/tags [get]
+func (h *ShortHandler) GetTags(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	tags, err := h.service.GetTags(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return c.JSON(names)
+}
+
+// GetFolders godoc
+// @Summary List the authenticated user's folders
+// @Tags me
+// @Produce json
+// @Success 200 {array} FolderResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/folders [get]
+func (h *ShortHandler) GetFolders(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	folders, err := h.service.GetFolders(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]FolderResponse, 0, len(folders))
+	for _, folder := range folders {
+		responses = append(responses, NewFolderResponse(folder))
+	}
+	return c.JSON(responses)
+}
+
+// CreateFolder godoc
+// @Summary Create a folder
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body FolderCreateRequest true "FolderCreateRequest"
+// @Success 201 {object} FolderResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/me/folders [post]
+func (h *ShortHandler) CreateFolder(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req FolderCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	folder, err := h.service.CreateFolder(c.Context(), userID, req)
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(NewFolderResponse(folder))
+}
+
+// DeleteFolder godoc
+// @Summary Delete a folder
+// @Description The folder's shorts are kept and no longer belong to a folder
+// @Tags me
+// @Param id path int true "Folder ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/folders/{id} [delete]
+func (h *ShortHandler) DeleteFolder(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.service.DeleteFolder(c.Context(), userID, types.FolderId(id)); err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetCampaigns godoc
+// @Summary List the authenticated user's campaigns
+// @Tags me
+// @Produce json
+// @Success 200 {array} CampaignResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/me/campaigns [get]
+func (h *ShortHandler) GetCampaigns(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	campaigns, err := h.service.GetCampaigns(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	responses := make([]CampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		responses = append(responses, NewCampaignResponse(campaign))
+	}
+	return c.JSON(responses)
+}
+
+// CreateCampaign creates a campaign for the authenticated user. Shorts
+// created with the campaign's id get its UTM parameters merged into their
+// destination. utm_campaign defaults to the campaign name.
+//
+// CreateCampaign godoc
+// @Summary Create a campaign
+// @Description Creates a campaign with UTM parameters
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body CampaignCreateRequest true "CampaignCreateRequest"
+// @Success 201 {object} CampaignResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/v1/me/campaigns [post]
+func (h *ShortHandler) CreateCampaign(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var req CampaignCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request"})
+	}
+	if err := validate.Struct(req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	campaign, err := h.service.CreateCampaign(c.Context(), userID, req)
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(NewCampaignResponse(campaign))
+}
+
+// GetCampaign godoc
+// @Summary Get one of the authenticated user's campaigns
+// @Tags me
+// @Produce json
+// @Param id path int true "Campaign ID"
+// @Success 200 {object} CampaignResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/campaigns/{id} [get]
+func (h *ShortHandler) GetCampaign(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	campaign, err := h.service.GetCampaign(c.Context(), userID, types.CampaignId(id))
+	if err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(NewCampaignResponse(campaign))
+}
+
+// DeleteCampaign godoc
+// @Summary Delete a campaign
+// @Description The campaign's shorts are kept, UTM parameters included, and no longer belong to a campaign
+// @Tags me
+// @Param id path int true "Campaign ID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/v1/me/campaigns/{id} [delete]
+func (h *ShortHandler) DeleteCampaign(c *fiber.Ctx) error {
+	userID, _, err := middleware.CurrentUser(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if err := h.service.DeleteCampaign(c.Context(), userID, types.CampaignId(id)); err != nil {
+		return c.Status(shortErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
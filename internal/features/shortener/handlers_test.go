@@ -0,0 +1,76 @@
+package shortener
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/Kalmera74/Shorty/pkg/security"
+	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUnlockShort_PreviewPath(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	hash, _ := security.HashPassword("s3cret")
+	slug := "promo"
+	mockRedis.On("Get", mock.Anything, shortByShortUrlKey(0, slug)).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("Search", mock.MatchedBy(func(req SearchRequest) bool {
+		return req.ShortUrl != nil && *req.ShortUrl == slug
+	})).Return([]ShortModel{{ID: 1, ShortUrl: slug, PasswordHash: hash}}, nil)
+	mockRedis.On("Get", mock.Anything, unlockAttemptsKey(1)).Return("", redis.Nil)
+	mockRedis.On("Increment", mock.Anything, unlockAttemptsKey(1), unlockAttemptWindow).Return(int64(1), nil)
+
+	app := fiber.New()
+	RegisterRoutes(app, NewShortHandler(NewShortService(mockStore, mockRedis), nil))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/promo+", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	form := url.Values{"password": {"wrong"}}
+	req := httptest.NewRequest("POST", "/promo+", strings.NewReader(form.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	mockRedis.AssertCalled(t, "Increment", mock.Anything, unlockAttemptsKey(1), unlockAttemptWindow)
+}
+
+// goneClient fails every write, as a connection closed by the client does.
+type goneClient struct {
+	writes int
+}
+
+func (g *goneClient) Write(p []byte) (int, error) {
+	g.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestClientWriter_StopsExportWhenClientIsGone(t *testing.T) {
+	mockStore := new(MockStore)
+	userID := types.UserId(3)
+	batches := [][]ShortModel{{{ID: 1, ShortUrl: "a"}}, {{ID: 2, ShortUrl: "b"}}}
+	mockStore.On("Iterate", &userID, csvExportBatchSize).Return(batches, nil)
+
+	client := &goneClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service := NewShortService(mockStore, nil)
+	err := service.ExportCSV(ctx, &userID, &clientWriter{w: bufio.NewWriter(client), cancel: cancel})
+
+	assert.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Equal(t, 1, client.writes)
+}
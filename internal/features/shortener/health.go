@@ -0,0 +1,167 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/linkcheck"
+)
+
+// Defaults for HealthConfig fields left at zero.
+const (
+	DefaultHealthConcurrency  = 8
+	DefaultHealthHostInterval = time.Second
+	DefaultHealthBrokenAfter  = 3
+)
+
+// ILinkChecker tells whether a URL is reachable. *linkcheck.Checker
+// satisfies it.
+type ILinkChecker interface {
+	Check(ctx context.Context, url string) linkcheck.Result
+}
+
+// HealthConfig tunes CheckHealth.
+type HealthConfig struct {
+	// Concurrency is how many links are checked at the same time.
+	Concurrency int
+	// HostInterval is the least time between two checks of the same host.
+	HostInterval time.Duration
+	// BrokenAfter is how many failed checks in a row mark a link broken.
+	BrokenAfter int
+}
+
+// HealthReport sums up a CheckHealth run.
+type HealthReport struct {
+	Checked int
+	Broken  int
+}
+
+// WithLinkChecker lets CheckHealth check destinations, for the health
+// worker.
+func WithLinkChecker(checker ILinkChecker, config HealthConfig) ShortServiceOption {
+	return func(s *shortService) {
+		if config.Concurrency <= 0 {
+			config.Concurrency = DefaultHealthConcurrency
+		}
+		if config.HostInterval <= 0 {
+			config.HostInterval = DefaultHealthHostInterval
+		}
+		if config.BrokenAfter <= 0 {
+			config.BrokenAfter = DefaultHealthBrokenAfter
+		}
+		s.Checker = checker
+		s.Health = config
+	}
+}
+
+// next returns the health after a check with result at now.
+func (h LinkHealth) next(result linkcheck.Result, brokenAfter int, now time.Time) LinkHealth {
+	next := LinkHealth{
+		Status:    result.Status,
+		LatencyMs: result.Latency.Milliseconds(),
+		CheckedAt: &now,
+	}
+	if result.Healthy() {
+		return next
+	}
+
+	if result.Err != nil {
+		next.Error = result.Err.Error()
+	}
+	next.Failures = h.Failures + 1
+	next.Broken = next.Failures >= brokenAfter
+	return next
+}
+
+// CheckHealth checks the original URL of every enabled short, records the
+// outcome and marks a short broken once it failed BrokenAfter checks in a
+// row. A single successful check clears the mark.
+func (s *shortService) CheckHealth(ctx context.Context) (HealthReport, error) {
+	if s.Checker == nil {
+		return HealthReport{}, fmt.Errorf("%w: no link checker configured", ErrHealthCheckFail)
+	}
+
+	var (
+		report  HealthReport
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		limiter = linkcheck.NewHostLimiter(s.Health.HostInterval)
+		shorts  = make(chan ShortModel)
+		errs    = make(chan error, 1)
+	)
+
+	for i := 0; i < s.Health.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for short := range shorts {
+				health, err := s.checkShort(ctx, limiter, short)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				mu.Lock()
+				report.Checked++
+				if health.Broken {
+					report.Broken++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err := s.Repository.Iterate(ctx, nil, blocklistBatchSize, func(batch []ShortModel) error {
+		for _, short := range batch {
+			if short.Disabled {
+				continue
+			}
+			select {
+			case shorts <- short:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	close(shorts)
+	wg.Wait()
+
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	if err != nil {
+		return report, fmt.Errorf("%w: %v", ErrHealthCheckFail, err)
+	}
+	return report, nil
+}
+
+func (s *shortService) checkShort(ctx context.Context, limiter *linkcheck.HostLimiter, short ShortModel) (LinkHealth, error) {
+	target, err := url.Parse(short.OriginalUrl)
+	if err != nil {
+		return LinkHealth{}, err
+	}
+	if err := limiter.Wait(ctx, target.Hostname()); err != nil {
+		return LinkHealth{}, err
+	}
+
+	health := short.Health.next(s.Checker.Check(ctx, short.OriginalUrl), s.Health.BrokenAfter, time.Now())
+	if err := s.Repository.SetHealth(ctx, short.ID, health); err != nil {
+		return LinkHealth{}, err
+	}
+
+	// Redirects read the cached short, which has to learn about the change
+	// for the fallback URL to take over or give way.
+	if s.Cacher != nil && health.Broken != short.Health.Broken && short.FallbackUrl != "" {
+		s.Cacher.Delete(ctx, shortByShortUrlKey(short.DomainID, short.ShortUrl))
+	}
+	return health, nil
+}
@@ -0,0 +1,184 @@
+package shortener
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// ShortHistoryModel records one change of a short. Entries are only ever
+// appended; a rollback is recorded as another change. Version counts the
+// changes of a short from 1.
+type ShortHistoryModel struct {
+	ID        types.HistoryId `gorm:"primaryKey"`
+	CreatedAt time.Time       `json:"created_at"`
+	ShortID   types.ShortId   `gorm:"not null;uniqueIndex:idx_short_history_version,priority:1"`
+	Version   int             `gorm:"not null;uniqueIndex:idx_short_history_version,priority:2"`
+	// EditorID is the user who made the change.
+	EditorID types.UserId `gorm:"not null"`
+	// RolledBackTo is the version the change restored, nil for edits.
+	RolledBackTo *int          `json:"rolled_back_to,omitempty"`
+	Old          ShortSnapshot `gorm:"type:jsonb;serializer:json"`
+	New          ShortSnapshot `gorm:"type:jsonb;serializer:json"`
+}
+
+// ShortSnapshot holds everything about a short that UpdateShort can change.
+type ShortSnapshot struct {
+	OriginalUrl    string                `json:"original_url"`
+	ShortUrl       string                `json:"short_url"`
+	Enabled        bool                  `json:"enabled"`
+	RedirectStatus int                   `json:"redirect_status"`
+	ForwardQuery   bool                  `json:"forward_query"`
+	AlwaysPreview  bool                  `json:"always_preview"`
+	FolderID       *types.FolderId       `json:"folder_id,omitempty"`
+	Tags           []string              `json:"tags"`
+	ActivateAt     *time.Time            `json:"activate_at,omitempty"`
+	ComingSoonUrl  string                `json:"coming_soon_url,omitempty"`
+	FallbackUrl    string                `json:"fallback_url,omitempty"`
+	Destinations   []DestinationRequest  `json:"destinations,omitempty"`
+	Rules          []RedirectRuleRequest `json:"rules,omitempty"`
+	Variants       []VariantRequest      `json:"variants,omitempty"`
+}
+
+func newShortSnapshot(short ShortModel) ShortSnapshot {
+	snapshot := ShortSnapshot{
+		OriginalUrl:    short.OriginalUrl,
+		ShortUrl:       short.ShortUrl,
+		Enabled:        !short.Disabled,
+		RedirectStatus: redirectStatus(short),
+		ForwardQuery:   short.ForwardQuery,
+		AlwaysPreview:  short.AlwaysPreview,
+		FolderID:       short.FolderID,
+		Tags:           short.TagNames(),
+		ActivateAt:     short.ActivateAt,
+		ComingSoonUrl:  short.ComingSoonUrl,
+		FallbackUrl:    short.FallbackUrl,
+	}
+	for _, destination := range short.Destinations {
+		snapshot.Destinations = append(snapshot.Destinations, DestinationRequest{
+			Url:      destination.Url,
+			StartsAt: destination.StartsAt,
+			EndsAt:   destination.EndsAt,
+		})
+	}
+	for _, rule := range short.Rules {
+		snapshot.Rules = append(snapshot.Rules, RedirectRuleRequest{
+			OS:       rule.OS,
+			Device:   rule.Device,
+			Language: rule.Language,
+			Country:  rule.Country,
+			Url:      rule.Url,
+		})
+	}
+	for _, variant := range short.Variants {
+		snapshot.Variants = append(snapshot.Variants, VariantRequest{
+			Name:   variant.Name,
+			Url:    variant.Url,
+			Weight: variant.Weight,
+		})
+	}
+	return snapshot
+}
+
+// request returns the update that turns any version of the short into
+// snapshot, apart from the activation time, see Rollback.
+func (s ShortSnapshot) request() UpdateShortRequest {
+	folderID := types.FolderId(0)
+	if s.FolderID != nil {
+		folderID = *s.FolderID
+	}
+	tags := append([]string{}, s.Tags...)
+	destinations := append([]DestinationRequest{}, s.Destinations...)
+	rules := append([]RedirectRuleRequest{}, s.Rules...)
+	variants := append([]VariantRequest{}, s.Variants...)
+
+	return UpdateShortRequest{
+		OriginalUrl:    &s.OriginalUrl,
+		ShortUrl:       &s.ShortUrl,
+		Enabled:        &s.Enabled,
+		RedirectStatus: &s.RedirectStatus,
+		ForwardQuery:   &s.ForwardQuery,
+		AlwaysPreview:  &s.AlwaysPreview,
+		Tags:           &tags,
+		FolderID:       &folderID,
+		ActivateAt:     s.ActivateAt,
+		ComingSoonUrl:  &s.ComingSoonUrl,
+		FallbackUrl:    &s.FallbackUrl,
+		Destinations:   &destinations,
+		Rules:          &rules,
+		Variants:       &variants,
+	}
+}
+
+func (s ShortSnapshot) equal(other ShortSnapshot) bool {
+	a, errA := json.Marshal(s)
+	b, errB := json.Marshal(other)
+	return errA == nil && errB == nil && bytes.Equal(a, b)
+}
+
+// recordChange appends the change from before to after to the short's
+// history through repo, unless nothing changed.
+func recordChange(ctx context.Context, repo IShortRepository, editor types.UserId, shortID types.ShortId, before, after ShortSnapshot, rolledBackTo *int) error {
+	if before.equal(after) {
+		return nil
+	}
+	_, err := repo.AddHistory(ctx, ShortHistoryModel{
+		ShortID:      shortID,
+		EditorID:     editor,
+		RolledBackTo: rolledBackTo,
+		Old:          before,
+		New:          after,
+	})
+	return err
+}
+
+// GetHistory returns the changes of a short, newest first.
+func (s *shortService) GetHistory(ctx context.Context, id types.ShortId) ([]ShortHistoryModel, error) {
+	if _, err := s.Repository.GetById(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.Repository.ListHistory(ctx, id)
+}
+
+// Rollback restores the short as it was after the given version, or before
+// its first recorded change for version 0. The rollback goes through
+// UpdateShort, so destinations are checked against the URL policy again, the
+// short is restored in one transaction with its history entry and the cache
+// is invalidated. It is recorded as a change of its own.
+func (s *shortService) Rollback(ctx context.Context, editor types.UserId, id types.ShortId, version int) (ShortModel, error) {
+	if version < 0 {
+		return ShortModel{}, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	current, err := s.Repository.GetById(ctx, id)
+	if err != nil {
+		return ShortModel{}, err
+	}
+
+	var snapshot ShortSnapshot
+	if version == 0 {
+		first, err := s.Repository.GetHistoryVersion(ctx, id, 1)
+		if err != nil {
+			return ShortModel{}, err
+		}
+		snapshot = first.Old
+	} else {
+		entry, err := s.Repository.GetHistoryVersion(ctx, id, version)
+		if err != nil {
+			return ShortModel{}, err
+		}
+		snapshot = entry.New
+	}
+
+	req := snapshot.request()
+	// An update cannot clear the activation time. Activating the short right
+	// away has the same effect.
+	if snapshot.ActivateAt == nil && !current.IsActive(time.Now()) {
+		now := time.Now()
+		req.ActivateAt = &now
+	}
+	return s.updateShort(ctx, editor, id, req, &version)
+}
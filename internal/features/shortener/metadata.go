@@ -0,0 +1,96 @@
+package shortener
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/Kalmera74/Shorty/pkg/messaging"
+	"github.com/Kalmera74/Shorty/pkg/metadata"
+)
+
+// DefaultMetadataQueue is the queue metadata jobs are published to when
+// METADATA_QUEUE is not set.
+const DefaultMetadataQueue = "metadata_queue"
+
+// MetadataJob asks the metadata worker to fetch the page a short points to.
+type MetadataJob struct {
+	ShortID types.ShortId `json:"short_id"`
+	Url     string        `json:"url"`
+}
+
+// IMetadataFetcher reads the title, description and icons of a page.
+// *metadata.Fetcher satisfies it.
+type IMetadataFetcher interface {
+	Fetch(ctx context.Context, url string) (metadata.Metadata, error)
+}
+
+// WithMetadataJobs publishes a MetadataJob to queue for every new short and
+// every change of destination.
+func WithMetadataJobs(mq messaging.IMessaging, queue string) ShortServiceOption {
+	return func(s *shortService) {
+		s.Jobs = mq
+		s.MetadataQueue = queue
+	}
+}
+
+// WithMetadataFetcher lets RefreshMetadata fetch pages, for the metadata
+// worker.
+func WithMetadataFetcher(fetcher IMetadataFetcher) ShortServiceOption {
+	return func(s *shortService) {
+		s.Fetcher = fetcher
+	}
+}
+
+// requestMetadata queues a metadata job for short. Metadata only decorates
+// listings, so a job that cannot be published is dropped rather than failing
+// the request that created the short.
+func (s *shortService) requestMetadata(short ShortModel) {
+	if s.Jobs == nil || short.ID == 0 {
+		return
+	}
+	payload, err := json.Marshal(MetadataJob{ShortID: short.ID, Url: short.OriginalUrl})
+	if err != nil {
+		return
+	}
+	_ = s.Jobs.Publish(s.MetadataQueue, payload)
+}
+
+// RefreshMetadata fetches the page of a metadata job and stores what it
+// says about itself on the short. Jobs for a destination the short no longer
+// has are skipped. A page that cannot be fetched is still marked as fetched,
+// with empty metadata, so it is not retried on every job.
+func (s *shortService) RefreshMetadata(ctx context.Context, job MetadataJob) error {
+	if s.Fetcher == nil {
+		return fmt.Errorf("%w: no fetcher configured", ErrMetadataFetchFail)
+	}
+
+	short, err := s.Repository.GetById(ctx, job.ShortID)
+	if err != nil {
+		return err
+	}
+	if short.OriginalUrl != job.Url {
+		return nil
+	}
+
+	page, fetchErr := s.Fetcher.Fetch(ctx, job.Url)
+	fetchedAt := time.Now()
+	meta := LinkMetadata{
+		Title:       page.Title,
+		Description: page.Description,
+		SiteName:    page.SiteName,
+		ImageUrl:    page.ImageUrl,
+		FaviconUrl:  page.FaviconUrl,
+		FetchedAt:   &fetchedAt,
+	}
+	if err := s.Repository.SetMetadata(ctx, short.ID, meta); err != nil {
+		return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+	}
+
+	if fetchErr != nil {
+		return fmt.Errorf("%w: %v", ErrMetadataFetchFail, fetchErr)
+	}
+	return nil
+}
@@ -1,17 +1,244 @@
 package shortener
 
 import (
+	"strings"
 	"time"
 
 	"github.com/Kalmera74/Shorty/internal/types"
+	"gorm.io/gorm"
 )
 
 type ShortModel struct {
-	ID          types.ShortId `gorm:"primaryKey"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	DeletedAt   time.Time     `json:"deleted_at,omitempty" gorm:"index"`
-	UserID      types.UserId  `gorm:"not null" validate:"required,numeric,min=1"`
-	OriginalUrl string        `gorm:"not null" validate:"required,url"`
-	ShortUrl    string        `gorm:"unique;not null" validate:"required"`
+	ID        types.ShortId `gorm:"primaryKey"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	// DeletedAt is set while the short is in the trash. Trashed shorts keep
+	// their row, and with it their short URL, until they are purged.
+	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	UserID    types.UserId   `gorm:"not null" validate:"required,numeric,min=1"`
+	// DomainID is the custom domain serving the short, 0 for the shared host.
+	// Short URLs are unique per domain.
+	DomainID       types.DomainId  `json:"domain_id" gorm:"not null;default:0;uniqueIndex:idx_shorts_domain_short_url,priority:1"`
+	OriginalUrl    string          `gorm:"not null" validate:"required,url"`
+	ShortUrl       string          `gorm:"not null;uniqueIndex:idx_shorts_domain_short_url,priority:2" validate:"required"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	MaxClicks      *int            `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	ClickCount     int             `json:"click_count" gorm:"not null;default:0"`
+	PasswordHash   string          `json:"password_hash,omitempty"`
+	Disabled       bool            `json:"disabled" gorm:"not null;default:false"`
+	RedirectStatus int             `json:"redirect_status" gorm:"not null;default:302"`
+	ForwardQuery   bool            `json:"forward_query" gorm:"not null;default:false"`
+	AlwaysPreview  bool            `json:"always_preview" gorm:"not null;default:false"`
+	FolderID       *types.FolderId `json:"folder_id,omitempty" gorm:"index"`
+	// CampaignID is the campaign the short was created under, if any.
+	CampaignID *types.CampaignId `json:"campaign_id,omitempty" gorm:"index"`
+	Tags       []TagModel        `json:"tags,omitempty" gorm:"many2many:short_tags"`
+	// ActivateAt holds redirects back until the given time. Visitors arriving
+	// earlier get a 404, or are sent to ComingSoonUrl when one is set.
+	ActivateAt    *time.Time         `json:"activate_at,omitempty"`
+	ComingSoonUrl string             `json:"coming_soon_url,omitempty"`
+	Destinations  []DestinationModel `json:"destinations,omitempty" gorm:"foreignKey:ShortID;constraint:OnDelete:CASCADE"`
+	// Rules are tried in order before any other destination, see Target.
+	Rules []RedirectRuleModel `json:"rules,omitempty" gorm:"foreignKey:ShortID;constraint:OnDelete:CASCADE"`
+	// Variants split the visitors between destinations by weight for A/B
+	// tests. A visitor keeps the variant they were first given.
+	Variants []VariantModel `json:"variants,omitempty" gorm:"foreignKey:ShortID;constraint:OnDelete:CASCADE"`
+	// BlockedReason is set while one of the short's destinations is on the
+	// URL blocklist. Blocked shorts do not redirect.
+	BlockedReason string `json:"blocked_reason,omitempty"`
+	// Metadata is filled in by the metadata worker after the short is
+	// created or retargeted.
+	Metadata LinkMetadata `json:"metadata" gorm:"embedded;embeddedPrefix:meta_"`
+	// Health is the outcome of the health worker's last checks of
+	// OriginalUrl.
+	Health LinkHealth `json:"health" gorm:"embedded;embeddedPrefix:health_"`
+	// FallbackUrl replaces OriginalUrl while the health worker finds it
+	// broken.
+	FallbackUrl string `json:"fallback_url,omitempty"`
+	// History is never loaded with the short, see GetHistory.
+	History []ShortHistoryModel `json:"-" gorm:"foreignKey:ShortID;constraint:OnDelete:CASCADE"`
+}
+
+// LinkHealth is what the health worker found when it last requested the
+// original URL. Broken is set once Failures reaches the configured number of
+// failed checks in a row. CheckedAt is nil until the first check.
+type LinkHealth struct {
+	Status    int        `json:"status,omitempty"`
+	LatencyMs int64      `json:"latency_ms"`
+	Error     string     `json:"error,omitempty"`
+	Failures  int        `json:"failures" gorm:"not null;default:0"`
+	Broken    bool       `json:"broken" gorm:"not null;default:false"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+}
+
+// LinkMetadata is what the destination page says about itself in its title,
+// description and Open Graph tags. FetchedAt is nil until the page has been
+// fetched.
+type LinkMetadata struct {
+	Title       string     `json:"title,omitempty"`
+	Description string     `json:"description,omitempty"`
+	SiteName    string     `json:"site_name,omitempty"`
+	ImageUrl    string     `json:"image_url,omitempty"`
+	FaviconUrl  string     `json:"favicon_url,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+}
+
+// VariantModel is one destination of an A/B test. Visitors are assigned to a
+// variant with a probability of its weight over the sum of all weights.
+type VariantModel struct {
+	ID      types.VariantId `gorm:"primaryKey"`
+	ShortID types.ShortId   `gorm:"not null;index"`
+	Name    string          `gorm:"not null"`
+	Url     string          `gorm:"not null"`
+	Weight  int             `gorm:"not null;default:1"`
+}
+
+// RedirectRuleModel sends visitors matching every non-empty condition to
+// Url. OS and Device take the values of the useragent package, Language a
+// tag such as "de" that also matches regional variants like "de-at", and
+// Country an upper-case ISO 3166-1 alpha-2 code.
+type RedirectRuleModel struct {
+	ID       types.RuleId  `gorm:"primaryKey"`
+	ShortID  types.ShortId `gorm:"not null;index"`
+	OS       string        `json:"os,omitempty"`
+	Device   string        `json:"device,omitempty"`
+	Language string        `json:"language,omitempty"`
+	Country  string        `json:"country,omitempty"`
+	Url      string        `gorm:"not null"`
+}
+
+// Visitor describes the client following a short, as far as redirect rules
+// care.
+type Visitor struct {
+	OS       string
+	Device   string
+	Language string
+	Country  string
+	// Variant is the A/B variant the visitor was assigned, 0 for none.
+	Variant types.VariantId
+}
+
+// Matches reports whether the rule applies to visitor.
+func (r RedirectRuleModel) Matches(visitor Visitor) bool {
+	if r.OS != "" && r.OS != visitor.OS {
+		return false
+	}
+	if r.Device != "" && r.Device != visitor.Device {
+		return false
+	}
+	if r.Language != "" && visitor.Language != r.Language && !strings.HasPrefix(visitor.Language, r.Language+"-") {
+		return false
+	}
+	if r.Country != "" && r.Country != visitor.Country {
+		return false
+	}
+	return true
+}
+
+// needsCountry reports whether any of the short's rules depends on the
+// visitor's country, which takes a GeoIP lookup to find.
+func (s ShortModel) needsCountry() bool {
+	for _, rule := range s.Rules {
+		if rule.Country != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// DestinationModel sends a short's visitors to Url while the current time is
+// within [StartsAt, EndsAt). A missing bound leaves that side of the window
+// open.
+type DestinationModel struct {
+	ID       types.DestinationId `gorm:"primaryKey"`
+	ShortID  types.ShortId       `gorm:"not null;index"`
+	Url      string              `gorm:"not null"`
+	StartsAt *time.Time          `json:"starts_at,omitempty"`
+	EndsAt   *time.Time          `json:"ends_at,omitempty"`
+}
+
+// Contains reports whether now falls within the destination's window.
+func (d DestinationModel) Contains(now time.Time) bool {
+	return (d.StartsAt == nil || !now.Before(*d.StartsAt)) &&
+		(d.EndsAt == nil || now.Before(*d.EndsAt))
+}
+
+// TagModel is a label a user attaches to any number of their shorts. Names
+// are unique per user.
+type TagModel struct {
+	ID        types.TagId  `gorm:"primaryKey"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    types.UserId `gorm:"not null;uniqueIndex:idx_tags_user_name,priority:1"`
+	Name      string       `gorm:"not null;uniqueIndex:idx_tags_user_name,priority:2"`
+}
+
+// FolderModel groups a user's shorts. A short is in at most one folder.
+type FolderModel struct {
+	ID        types.FolderId `gorm:"primaryKey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	UserID    types.UserId   `gorm:"not null;uniqueIndex:idx_folders_user_name,priority:1"`
+	Name      string         `gorm:"not null;uniqueIndex:idx_folders_user_name,priority:2" validate:"required,max=64"`
+}
+
+// TagNames returns the names of the short's tags.
+func (s ShortModel) TagNames() []string {
+	names := make([]string, 0, len(s.Tags))
+	for _, tag := range s.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// IsExpired reports whether the short's absolute expiry time has passed.
+func (s ShortModel) IsExpired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
+
+// IsActive reports whether the short's activation time, if any, has come.
+func (s ShortModel) IsActive(now time.Time) bool {
+	return s.ActivateAt == nil || !now.Before(*s.ActivateAt)
+}
+
+// Target returns the URL to send visitor to at now, and the A/B variant it
+// belongs to or 0. The first matching redirect rule wins, then the scheduled
+// destination for now, then the visitor's variant and finally the original
+// URL, or the fallback URL while the original one is broken.
+func (s ShortModel) Target(visitor Visitor, now time.Time) (string, types.VariantId) {
+	for _, rule := range s.Rules {
+		if rule.Matches(visitor) {
+			return rule.Url, 0
+		}
+	}
+	if destination, ok := s.scheduledAt(now); ok {
+		return destination, 0
+	}
+	if variant, ok := s.variant(visitor.Variant); ok {
+		return variant.Url, variant.ID
+	}
+	if s.Health.Broken && s.FallbackUrl != "" {
+		return s.FallbackUrl, 0
+	}
+	return s.OriginalUrl, 0
+}
+
+func (s ShortModel) scheduledAt(now time.Time) (string, bool) {
+	for _, destination := range s.Destinations {
+		if destination.Contains(now) {
+			return destination.Url, true
+		}
+	}
+	return "", false
+}
+
+// IsProtected reports whether the short requires a password before redirecting.
+func (s ShortModel) IsProtected() bool {
+	return s.PasswordHash != ""
+}
+
+func (s ShortModel) hasOptions() bool {
+	return s.ExpiresAt != nil || s.MaxClicks != nil || s.IsProtected() ||
+		redirectStatus(s) != DefaultRedirectStatus || s.ForwardQuery || s.AlwaysPreview ||
+		s.ActivateAt != nil || len(s.Destinations) > 0 || len(s.Rules) > 0 || len(s.Variants) > 0 ||
+		s.FallbackUrl != "" || s.CampaignID != nil
 }
@@ -0,0 +1,61 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// normalizeTags trims and lower-cases tag names and drops empty and repeated
+// ones, keeping the first occurrence order.
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+func (s *shortService) GetTags(ctx context.Context, userID types.UserId) ([]TagModel, error) {
+	return s.Repository.ListTags(ctx, userID)
+}
+
+func (s *shortService) CreateFolder(ctx context.Context, userID types.UserId, req FolderCreateRequest) (FolderModel, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return FolderModel{}, fmt.Errorf("%w: empty folder name", ErrInvalidUpdateRequest)
+	}
+	return s.Repository.CreateFolder(ctx, FolderModel{UserID: userID, Name: name})
+}
+
+func (s *shortService) GetFolders(ctx context.Context, userID types.UserId) ([]FolderModel, error) {
+	return s.Repository.ListFolders(ctx, userID)
+}
+
+func (s *shortService) DeleteFolder(ctx context.Context, userID types.UserId, id types.FolderId) error {
+	if _, err := s.folderForUser(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.Repository.DeleteFolder(ctx, id)
+}
+
+// folderForUser returns the folder when it belongs to userID. Other users'
+// folders are reported as missing rather than forbidden.
+func (s *shortService) folderForUser(ctx context.Context, userID types.UserId, id types.FolderId) (FolderModel, error) {
+	folder, err := s.Repository.GetFolder(ctx, id)
+	if err != nil {
+		return FolderModel{}, err
+	}
+	if folder.UserID != userID {
+		return FolderModel{}, fmt.Errorf("%w: %d", ErrFolderNotFound, id)
+	}
+	return folder, nil
+}
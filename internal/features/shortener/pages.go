@@ -0,0 +1,74 @@
+package shortener
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+</head>
+<body>
+<h1>This link is password protected</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post">
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="off" required autofocus>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type unlockPageData struct {
+	Error string
+}
+
+func renderUnlockPage(c *fiber.Ctx, status int, message string) error {
+	var buf bytes.Buffer
+	if err := unlockPage.Execute(&buf, unlockPageData{Error: message}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).Send(buf.Bytes())
+}
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Link preview</title>
+</head>
+<body>
+<h1>You are about to leave for</h1>
+<p><code>{{.Destination}}</code></p>
+<dl>
+{{if .Creator}}<dt>Created by</dt><dd>{{.Creator}}</dd>{{end}}
+<dt>Created on</dt><dd><time datetime="{{.CreatedAt.Format "2006-01-02T15:04:05Z07:00"}}">{{.CreatedAt.Format "January 2, 2006"}}</time></dd>
+</dl>
+<p><a href="{{.ContinueUrl}}" rel="noreferrer">Continue</a></p>
+</body>
+</html>
+`))
+
+func renderPreviewPage(c *fiber.Ctx, preview PreviewResponse) error {
+	var buf bytes.Buffer
+	if err := previewPage.Execute(&buf, preview); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusOK).Send(buf.Bytes())
+}
@@ -0,0 +1,277 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/Kalmera74/Shorty/pkg/safehttp"
+	"github.com/Kalmera74/Shorty/pkg/wordlist"
+)
+
+// DefaultAllowedSchemes are the schemes a short may point to unless the
+// policy says otherwise.
+var DefaultAllowedSchemes = []string{"http", "https"}
+
+// blocklistBatchSize is how many shorts EnforceBlocklist loads at a time.
+const blocklistBatchSize = 500
+
+// IHostResolver looks up the addresses of a host name. *net.Resolver
+// satisfies it.
+type IHostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// ScanVerdict is what an IURLScanner found out about a URL.
+type ScanVerdict struct {
+	Unsafe bool
+	// Reason names the threat, such as "phishing" or "malware".
+	Reason string
+}
+
+// IURLScanner checks a URL against an external reputation service such as a
+// phishing feed.
+type IURLScanner interface {
+	Scan(ctx context.Context, url string) (ScanVerdict, error)
+}
+
+// URLPolicy decides which URLs a short may send visitors to.
+type URLPolicy struct {
+	AllowedSchemes []string
+	// AllowPrivate lets shorts point at loopback, private and link-local
+	// addresses, which is only wanted for installations on a closed network.
+	AllowPrivate bool
+	// Blocklist holds domains that may not be linked to, along with all of
+	// their subdomains.
+	Blocklist *wordlist.List
+	// Resolver, when set, is used to reject host names that resolve to a
+	// private address.
+	Resolver IHostResolver
+	// Scanner, when set, is asked about every URL that passed the local
+	// checks. URLs are let through when the scanner cannot be reached.
+	Scanner IURLScanner
+}
+
+// DefaultURLPolicy allows http and https links to public hosts.
+func DefaultURLPolicy() *URLPolicy {
+	return &URLPolicy{AllowedSchemes: DefaultAllowedSchemes}
+}
+
+// WithURLPolicy replaces the default URL policy.
+func WithURLPolicy(policy *URLPolicy) ShortServiceOption {
+	return func(s *shortService) {
+		if policy != nil {
+			s.Policy = policy
+		}
+	}
+}
+
+// Check returns ErrUnsafeURL when raw may not be shortened.
+func (p *URLPolicy) Check(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnsafeURL, err)
+	}
+
+	if !p.allowsScheme(u.Scheme) {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrUnsafeURL, u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: %s has no host", ErrUnsafeURL, raw)
+	}
+
+	if domain, ok := p.blockedDomain(host); ok {
+		return fmt.Errorf("%w: domain %s is blocked", ErrUnsafeURL, domain)
+	}
+
+	if !p.AllowPrivate && p.isPrivateHost(ctx, host) {
+		return fmt.Errorf("%w: %s is a private address", ErrUnsafeURL, host)
+	}
+
+	if p.Scanner != nil {
+		verdict, err := p.Scanner.Scan(ctx, raw)
+		if err == nil && verdict.Unsafe {
+			return fmt.Errorf("%w: flagged as %s", ErrUnsafeURL, verdict.Reason)
+		}
+	}
+
+	return nil
+}
+
+func (p *URLPolicy) allowsScheme(scheme string) bool {
+	for _, allowed := range p.AllowedSchemes {
+		if strings.EqualFold(strings.TrimSpace(allowed), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedDomain returns the entry of the blocklist host falls under, trying
+// host itself and then each parent domain.
+func (p *URLPolicy) blockedDomain(host string) (string, bool) {
+	if net.ParseIP(host) != nil {
+		return host, p.Blocklist.Has(host)
+	}
+
+	domain := host
+	for {
+		if p.Blocklist.Has(domain) {
+			return domain, true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			return "", false
+		}
+		domain = parent
+	}
+}
+
+func (p *URLPolicy) isPrivateHost(ctx context.Context, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return safehttp.IsPrivateIP(ip)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") ||
+		strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return true
+	}
+
+	if p.Resolver == nil {
+		return false
+	}
+	// A name that does not resolve cannot reach a private network either,
+	// and may simply not be set up yet.
+	addrs, err := p.Resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if safehttp.IsPrivateIP(addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkURLs runs each non-empty URL through the policy.
+func (s *shortService) checkURLs(ctx context.Context, urls []string) error {
+	for _, raw := range urls {
+		if raw == "" {
+			continue
+		}
+		if err := s.Policy.Check(ctx, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockReason returns why short must be blocked under the current blocklist,
+// or "" when none of its destinations is on it.
+func (s *shortService) blockReason(short ShortModel) string {
+	for _, raw := range short.targetURLs() {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+		if domain, ok := s.Policy.blockedDomain(host); ok {
+			return "blocklisted domain " + domain
+		}
+	}
+	return ""
+}
+
+// EnforceBlocklist blocks the shorts that point to a domain on the
+// blocklist and lifts the block from shorts whose domains were taken off it.
+// It is run whenever the blocklist changes and returns how many shorts it
+// changed.
+func (s *shortService) EnforceBlocklist(ctx context.Context) (int, error) {
+	changed := 0
+	err := s.Repository.Iterate(ctx, nil, blocklistBatchSize, func(batch []ShortModel) error {
+		for _, short := range batch {
+			reason := s.blockReason(short)
+			if reason == short.BlockedReason {
+				continue
+			}
+			if err := s.Repository.SetBlockedReason(ctx, short.ID, reason); err != nil {
+				return err
+			}
+			s.Cacher.Delete(ctx, shortByShortUrlKey(short.DomainID, short.ShortUrl))
+			changed++
+		}
+		return nil
+	})
+	if err != nil {
+		return changed, fmt.Errorf("could not enforce blocklist: %w", err)
+	}
+	return changed, nil
+}
+
+func (r ShortenRequest) targetURLs() []string {
+	urls := []string{r.Url}
+	if r.ComingSoonUrl != nil {
+		urls = append(urls, *r.ComingSoonUrl)
+	}
+	if r.FallbackUrl != nil {
+		urls = append(urls, *r.FallbackUrl)
+	}
+	for _, destination := range r.Destinations {
+		urls = append(urls, destination.Url)
+	}
+	for _, rule := range r.Rules {
+		urls = append(urls, rule.Url)
+	}
+	for _, variant := range r.Variants {
+		urls = append(urls, variant.Url)
+	}
+	return urls
+}
+
+func (r UpdateShortRequest) targetURLs() []string {
+	var urls []string
+	if r.OriginalUrl != nil {
+		urls = append(urls, *r.OriginalUrl)
+	}
+	if r.ComingSoonUrl != nil {
+		urls = append(urls, *r.ComingSoonUrl)
+	}
+	if r.FallbackUrl != nil {
+		urls = append(urls, *r.FallbackUrl)
+	}
+	if r.Destinations != nil {
+		for _, destination := range *r.Destinations {
+			urls = append(urls, destination.Url)
+		}
+	}
+	if r.Rules != nil {
+		for _, rule := range *r.Rules {
+			urls = append(urls, rule.Url)
+		}
+	}
+	if r.Variants != nil {
+		for _, variant := range *r.Variants {
+			urls = append(urls, variant.Url)
+		}
+	}
+	return urls
+}
+
+func (s ShortModel) targetURLs() []string {
+	urls := []string{s.OriginalUrl, s.ComingSoonUrl, s.FallbackUrl}
+	for _, destination := range s.Destinations {
+		urls = append(urls, destination.Url)
+	}
+	for _, rule := range s.Rules {
+		urls = append(urls, rule.Url)
+	}
+	for _, variant := range s.Variants {
+		urls = append(urls, variant.Url)
+	}
+	return urls
+}
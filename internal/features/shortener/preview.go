@@ -0,0 +1,100 @@
+package shortener
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// previewSuffix appended to a short URL asks for the preview page.
+	previewSuffix = "+"
+	// continueParam marks the request sent by the preview page's continue
+	// button, which redirects even when the short always previews.
+	continueParam = "continue"
+)
+
+// ICreatorLookup finds the name to show for the creator of a short. It is
+// implemented by the user feature; without one previews leave the creator
+// out.
+type ICreatorLookup interface {
+	UserName(ctx context.Context, id types.UserId) (string, error)
+}
+
+// WithCreatorLookup shows the creator's name on preview pages.
+func WithCreatorLookup(creators ICreatorLookup) ShortServiceOption {
+	return func(s *shortService) {
+		s.Creators = creators
+	}
+}
+
+// CreatorName returns the name of the user who created short, or "" when it
+// cannot be told.
+func (s *shortService) CreatorName(ctx context.Context, short ShortModel) string {
+	if s.Creators == nil {
+		return ""
+	}
+	name, err := s.Creators.UserName(ctx, short.UserID)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// wantsPreview reports whether the visitor should see the preview page
+// rather than be redirected.
+func wantsPreview(c *fiber.Ctx, short ShortModel, suffixed bool) bool {
+	return suffixed || (short.AlwaysPreview && c.Query(continueParam) == "")
+}
+
+// forwardedQuery returns the query string of the visit without the
+// parameter added by the preview page.
+func forwardedQuery(c *fiber.Ctx) string {
+	query := string(c.Request().URI().QueryString())
+	if c.Query(continueParam) == "" {
+		return query
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	values.Del(continueParam)
+	return values.Encode()
+}
+
+// continueURL is where the preview page's continue button leads: the short
+// itself, keeping the visitor's query string for shorts that forward it.
+func continueURL(c *fiber.Ctx, shortUrl string) string {
+	values, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if values == nil {
+		values = url.Values{}
+	}
+	values.Set(continueParam, "1")
+	return "/" + strings.TrimSuffix(shortUrl, previewSuffix) + "?" + values.Encode()
+}
+
+// preview answers with the preview page, or its JSON form for clients that
+// prefer JSON. The visit is not counted as a click.
+func (h *ShortHandler) preview(c *fiber.Ctx, short ShortModel) error {
+	// The visitor continues to the variant they were shown.
+	destination, variantID := short.Target(h.visitor(c, short), time.Now())
+	pinVariant(c, short.ID, variantID)
+	response := PreviewResponse{
+		ShortUrl:    short.ShortUrl,
+		Destination: destination,
+		Creator:     h.service.CreatorName(c.Context(), short),
+		CreatedAt:   short.CreatedAt,
+		ContinueUrl: continueURL(c, short.ShortUrl),
+	}
+
+	c.Vary(fiber.HeaderAccept)
+	if c.Accepts(fiber.MIMETextHTML, fiber.MIMEApplicationJSON) == fiber.MIMEApplicationJSON {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.JSON(response)
+	}
+	return renderPreviewPage(c, response)
+}
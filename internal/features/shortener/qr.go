@@ -0,0 +1,96 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/qrcode"
+)
+
+const (
+	qrCacheTTL = time.Hour * 24
+
+	defaultQRFormat = "png"
+	defaultQRSize   = 256
+	defaultQRLevel  = "M"
+	defaultQRFg     = "000000"
+	defaultQRBg     = "ffffff"
+)
+
+// withDefaults fills in the options the caller left out and normalises the
+// rest, so equal images share one cache entry.
+func (req QRCodeRequest) withDefaults() QRCodeRequest {
+	if req.Format == "" {
+		req.Format = defaultQRFormat
+	}
+	if req.Size == 0 {
+		req.Size = defaultQRSize
+	}
+	if req.Level == "" {
+		req.Level = defaultQRLevel
+	}
+	if req.Foreground == "" {
+		req.Foreground = defaultQRFg
+	}
+	if req.Background == "" {
+		req.Background = defaultQRBg
+	}
+
+	req.Format = strings.ToLower(req.Format)
+	req.Level = strings.ToUpper(req.Level)
+	req.Foreground = strings.ToLower(strings.TrimPrefix(req.Foreground, "#"))
+	req.Background = strings.ToLower(strings.TrimPrefix(req.Background, "#"))
+	return req
+}
+
+// QRCode renders link as a QR code image in the format of req. Rendered
+// images are cached, keyed by the link and every option.
+func (s *shortService) QRCode(ctx context.Context, link string, req QRCodeRequest) ([]byte, error) {
+	req = req.withDefaults()
+
+	level, err := qrcode.ParseLevel(req.Level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQRRequest, err)
+	}
+	foreground, err := qrcode.ParseHexColor(req.Foreground)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQRRequest, err)
+	}
+	background, err := qrcode.ParseHexColor(req.Background)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQRRequest, err)
+	}
+
+	key := qrCodeKey(link, req)
+	if cached, err := s.Cacher.Get(ctx, key); err == nil && cached != "" {
+		return []byte(cached), nil
+	}
+
+	code, err := qrcode.Encode([]byte(link), level)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQRRequest, err)
+	}
+
+	var image []byte
+	switch req.Format {
+	case "svg":
+		image = code.SVG(req.Size, foreground, background)
+	default:
+		image, err = code.PNG(req.Size, foreground, background)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.Cacher.Set(ctx, key, image, qrCacheTTL)
+	return image, nil
+}
+
+func qrCodeKey(link string, req QRCodeRequest) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%s|%s", link, req.Format, req.Size, req.Level, req.Foreground, req.Background)))
+	return "short:qr:" + hex.EncodeToString(sum[:])
+}
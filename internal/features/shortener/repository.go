@@ -4,19 +4,81 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IShortRepository interface {
 	Create(ctx context.Context, short ShortModel) (ShortModel, error)
 	GetById(ctx context.Context, id types.ShortId) (ShortModel, error)
 	Search(ctx context.Context, req SearchRequest) ([]ShortModel, error)
-	GetAll(ctx context.Context, offset, limit int) ([]ShortModel, int, error)
+	GetAll(ctx context.Context, params pagination.Params) ([]ShortModel, pagination.Page, error)
 	Delete(ctx context.Context, shortenID types.ShortId) error
+	ListTrash(ctx context.Context, userID *types.UserId) ([]ShortModel, error)
+	GetDeleted(ctx context.Context, id types.ShortId) (ShortModel, error)
+	Restore(ctx context.Context, id types.ShortId) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	ConsumeClick(ctx context.Context, id types.ShortId) error
+	Update(ctx context.Context, short ShortModel) (ShortModel, error)
+	Transaction(ctx context.Context, fn func(repo IShortRepository) error) error
+	Iterate(ctx context.Context, userID *types.UserId, batchSize int, fn func(batch []ShortModel) error) error
+	EnsureTags(ctx context.Context, userID types.UserId, names []string) ([]TagModel, error)
+	SetTags(ctx context.Context, shortID types.ShortId, tags []TagModel) error
+	SetDestinations(ctx context.Context, shortID types.ShortId, destinations []DestinationModel) error
+	SetRules(ctx context.Context, shortID types.ShortId, rules []RedirectRuleModel) error
+	SetVariants(ctx context.Context, shortID types.ShortId, variants []VariantModel) error
+	SetBlockedReason(ctx context.Context, shortID types.ShortId, reason string) error
+	SetMetadata(ctx context.Context, shortID types.ShortId, meta LinkMetadata) error
+	SetHealth(ctx context.Context, shortID types.ShortId, health LinkHealth) error
+	AddHistory(ctx context.Context, entry ShortHistoryModel) (ShortHistoryModel, error)
+	ListHistory(ctx context.Context, shortID types.ShortId) ([]ShortHistoryModel, error)
+	GetHistoryVersion(ctx context.Context, shortID types.ShortId, version int) (ShortHistoryModel, error)
+	ReleaseSlug(ctx context.Context, release ReleasedSlugModel) error
+	GetRelease(ctx context.Context, domainID types.DomainId, shortUrl string, since time.Time) (*ReleasedSlugModel, error)
+	DeleteReleases(ctx context.Context, before time.Time) error
+	ListTags(ctx context.Context, userID types.UserId) ([]TagModel, error)
+	CreateFolder(ctx context.Context, folder FolderModel) (FolderModel, error)
+	GetFolder(ctx context.Context, id types.FolderId) (FolderModel, error)
+	ListFolders(ctx context.Context, userID types.UserId) ([]FolderModel, error)
+	DeleteFolder(ctx context.Context, id types.FolderId) error
+	CreateCampaign(ctx context.Context, campaign CampaignModel) (CampaignModel, error)
+	GetCampaign(ctx context.Context, id types.CampaignId) (CampaignModel, error)
+	ListCampaigns(ctx context.Context, userID types.UserId) ([]CampaignModel, error)
+	DeleteCampaign(ctx context.Context, id types.CampaignId) error
 }
 
+// updatableColumns are the columns Update writes. Counters such as
+// click_count are maintained separately and never overwritten.
+var updatableColumns = []string{
+	"original_url", "short_url", "disabled", "redirect_status", "forward_query", "always_preview", "folder_id",
+	"activate_at", "coming_soon_url", "fallback_url",
+}
+
+// searchSortColumns are the columns Search may order by.
+var searchSortColumns = map[string]bool{
+	"created_at":   true,
+	"click_count":  true,
+	"short_url":    true,
+	"original_url": true,
+}
+
+// preloadRouting loads a short's scheduled destinations, redirect rules and
+// A/B variants in the order they were given, which is the order Target tries
+// them.
+func preloadRouting(db *gorm.DB) *gorm.DB {
+	byID := func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}
+	return db.Preload("Destinations", byID).Preload("Rules", byID).Preload("Variants", byID)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type postgresURLStore struct {
 	db *gorm.DB
 }
@@ -27,16 +89,23 @@ func NewShortRepository(db *gorm.DB) IShortRepository {
 
 func (s *postgresURLStore) Create(ctx context.Context, short ShortModel) (ShortModel, error) {
 
-	result := s.db.Create(&short)
-	if result.Error != nil {
-		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, result.Error)
+	// Inside Transaction this becomes a savepoint, so a unique violation does
+	// not abort the surrounding transaction and the caller can retry.
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&short).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortUrlTaken, short.ShortUrl)
+	}
+	if err != nil {
+		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
 	}
 	return short, nil
 }
 func (s *postgresURLStore) GetById(ctx context.Context, shortID types.ShortId) (ShortModel, error) {
 	var url ShortModel
 
-	result := s.db.First(&url, shortID)
+	result := s.db.WithContext(ctx).Preload("Tags").Scopes(preloadRouting).First(&url, shortID)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortNotFound, result.Error)
@@ -50,7 +119,7 @@ func (s *postgresURLStore) GetById(ctx context.Context, shortID types.ShortId) (
 }
 func (r *postgresURLStore) Search(ctx context.Context, req SearchRequest) ([]ShortModel, error) {
 	var shorts []ShortModel
-	query := r.db.WithContext(ctx).Model(&ShortModel{})
+	query := r.db.WithContext(ctx).Model(&ShortModel{}).Preload("Tags").Scopes(preloadRouting)
 
 	if req.OriginalUrl != nil {
 		query = query.Where("original_url = ?", *req.OriginalUrl)
@@ -61,8 +130,31 @@ func (r *postgresURLStore) Search(ctx context.Context, req SearchRequest) ([]Sho
 	if req.ShortUrl != nil {
 		query = query.Where("short_url =?", *req.ShortUrl)
 	}
+	if req.DomainID != nil {
+		query = query.Where("domain_id = ?", *req.DomainID)
+	}
+	if req.FolderID != nil {
+		query = query.Where("folder_id = ?", *req.FolderID)
+	}
+	if req.Tag != nil {
+		tagged := r.db.Table("short_tags").
+			Select("short_tags.short_model_id").
+			Joins("JOIN tag_models ON tag_models.id = short_tags.tag_model_id").
+			Where("tag_models.name = ?", *req.Tag)
+		query = query.Where("id IN (?)", tagged)
+	}
+	if req.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *req.CreatedFrom)
+	}
+	if req.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *req.CreatedTo)
+	}
+	if req.Query != nil {
+		pattern := "%" + likeEscaper.Replace(*req.Query) + "%"
+		query = query.Where("original_url ILIKE ? OR short_url ILIKE ?", pattern, pattern)
+	}
 
-	if err := query.Find(&shorts).Error; err != nil {
+	if err := query.Order(searchOrder(req.Sort)).Find(&shorts).Error; err != nil {
 		return nil, err
 	}
 
@@ -72,31 +164,50 @@ func (r *postgresURLStore) Search(ctx context.Context, req SearchRequest) ([]Sho
 
 	return shorts, nil
 }
-func (r *postgresURLStore) GetAll(ctx context.Context, offset, limit int) ([]ShortModel, int, error) {
+func (r *postgresURLStore) GetAll(ctx context.Context, params pagination.Params) ([]ShortModel, pagination.Page, error) {
 	var shorts []ShortModel
-	var total int64
 
-	if err := r.db.WithContext(ctx).Model(&ShortModel{}).Count(&total).Error; err != nil {
-		return nil, 0, fmt.Errorf("%w: %v", ErrShortNotFound, err)
+	total, err := params.Count(r.db.WithContext(ctx).Model(&ShortModel{}))
+	if err != nil {
+		return nil, pagination.Page{}, fmt.Errorf("%w: %v", ErrShortNotFound, err)
 	}
 
 	if err := r.db.WithContext(ctx).
-		Order("id DESC").
-		Offset(offset).
-		Limit(limit).
+		Scopes(params.Scope("id")).
 		Find(&shorts).Error; err != nil {
-		return nil, 0, fmt.Errorf("%w: %v", ErrShortNotFound, err)
+		return nil, pagination.Page{}, fmt.Errorf("%w: %v", ErrShortNotFound, err)
 	}
 
 	if len(shorts) == 0 {
-		return nil, -1, fmt.Errorf("%w", ErrShortNotFound)
+		return nil, pagination.Page{}, fmt.Errorf("%w", ErrShortNotFound)
+	}
+
+	shorts, next := pagination.Trim(shorts, params.Limit, func(s ShortModel) uint { return uint(s.ID) })
+	return shorts, pagination.Page{NextCursor: next, Total: total}, nil
+}
+
+func (s *postgresURLStore) Update(ctx context.Context, short ShortModel) (ShortModel, error) {
+	result := s.db.WithContext(ctx).
+		Model(&ShortModel{ID: short.ID}).
+		Select(updatableColumns).
+		Updates(&short)
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortUrlTaken, short.ShortUrl)
+	}
+	if result.Error != nil {
+		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortUpdateFail, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ShortModel{}, fmt.Errorf("%w: no URL found with ID %d", ErrShortNotFound, short.ID)
 	}
 
-	return shorts, int(total), nil
+	return short, nil
 }
 
+// Delete moves a short to the trash.
 func (s *postgresURLStore) Delete(ctx context.Context, shortId types.ShortId) error {
-	result := s.db.Find(shortId).Delete(&ShortModel{})
+	result := s.db.WithContext(ctx).Delete(&ShortModel{}, shortId)
 
 	if result.Error != nil {
 		return fmt.Errorf("%w: %v", ErrShortDeleteFail, result.Error)
@@ -108,3 +219,437 @@ func (s *postgresURLStore) Delete(ctx context.Context, shortId types.ShortId) er
 
 	return nil
 }
+
+// ListTrash returns the trashed shorts of userID, or of every user when
+// userID is nil, most recently deleted first.
+func (s *postgresURLStore) ListTrash(ctx context.Context, userID *types.UserId) ([]ShortModel, error) {
+	query := s.db.WithContext(ctx).Unscoped().Preload("Tags").Where("deleted_at IS NOT NULL")
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var shorts []ShortModel
+	if err := query.Order("deleted_at DESC").Find(&shorts).Error; err != nil {
+		return nil, err
+	}
+	return shorts, nil
+}
+
+// GetDeleted returns a short from the trash.
+func (s *postgresURLStore) GetDeleted(ctx context.Context, id types.ShortId) (ShortModel, error) {
+	var short ShortModel
+	result := s.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&short, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return ShortModel{}, fmt.Errorf("%w: %d is not in the trash", ErrShortNotFound, id)
+	}
+	if result.Error != nil {
+		return ShortModel{}, result.Error
+	}
+	return short, nil
+}
+
+// Restore takes a short out of the trash.
+func (s *postgresURLStore) Restore(ctx context.Context, id types.ShortId) error {
+	result := s.db.WithContext(ctx).Unscoped().
+		Model(&ShortModel{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return fmt.Errorf("%w: %v", ErrShortUpdateFail, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d is not in the trash", ErrShortNotFound, id)
+	}
+	return nil
+}
+
+// Purge deletes the shorts trashed before deletedBefore for good and returns
+// how many there were. Their tag links are deleted here; their clicks,
+// routing and history go with them through the ON DELETE CASCADE foreign
+// keys. Their short URLs are recorded as released.
+func (s *postgresURLStore) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var ids []types.ShortId
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&ShortModel{}).
+			Where("deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec(
+			"INSERT INTO released_slug_models (domain_id, short_url, user_id, released_at) "+
+				"SELECT domain_id, short_url, user_id, ? FROM short_models WHERE id IN ?", time.Now(), ids,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM short_tags WHERE short_model_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&ShortModel{}, ids).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrShortDeleteFail, err)
+	}
+	return len(ids), nil
+}
+
+// ConsumeClick counts one click against the short's max_clicks limit. The
+// check and the increment happen in a single statement so concurrent
+// redirects cannot overshoot the limit.
+func (s *postgresURLStore) ConsumeClick(ctx context.Context, id types.ShortId) error {
+	result := s.db.WithContext(ctx).
+		Model(&ShortModel{}).
+		Where("id = ? AND (max_clicks IS NULL OR click_count < max_clicks)", id).
+		UpdateColumn("click_count", gorm.Expr("click_count + 1"))
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrShortClickLimit, id)
+	}
+	return nil
+}
+
+// Transaction runs fn with a repository bound to a single database
+// transaction, which is committed only if fn returns nil.
+func (s *postgresURLStore) Transaction(ctx context.Context, fn func(repo IShortRepository) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&postgresURLStore{db: tx})
+	})
+}
+
+// Iterate calls fn with consecutive batches of shorts ordered by id, limited
+// to userID when it is set, so callers can walk every short without loading
+// them all at once. Returning an error from fn stops the iteration.
+func (s *postgresURLStore) Iterate(ctx context.Context, userID *types.UserId, batchSize int, fn func(batch []ShortModel) error) error {
+	query := s.db.WithContext(ctx).Model(&ShortModel{}).Preload("Tags").Scopes(preloadRouting)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var batch []ShortModel
+	result := query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	})
+	return result.Error
+}
+
+// searchOrder turns a sort option such as "-created_at" into an ORDER BY
+// clause, with the id as tie-breaker so pages are stable.
+func searchOrder(sort *string) string {
+	if sort == nil {
+		return "id"
+	}
+
+	column, descending := strings.CutPrefix(*sort, "-")
+	if !searchSortColumns[column] {
+		return "id"
+	}
+	if descending {
+		return column + " DESC, id DESC"
+	}
+	return column + ", id"
+}
+
+// EnsureTags returns the user's tags with the given names, creating the ones
+// that do not exist yet.
+func (s *postgresURLStore) EnsureTags(ctx context.Context, userID types.UserId, names []string) ([]TagModel, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	tags := make([]TagModel, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, TagModel{UserID: userID, Name: name})
+	}
+
+	// Updating the name to itself makes Postgres return the id of tags
+	// that already exist.
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// SetTags replaces the tags of a short.
+func (s *postgresURLStore) SetTags(ctx context.Context, shortID types.ShortId, tags []TagModel) error {
+	association := s.db.WithContext(ctx).Model(&ShortModel{ID: shortID}).Association("Tags")
+	if len(tags) == 0 {
+		return association.Clear()
+	}
+	return association.Replace(tags)
+}
+
+// SetDestinations replaces the scheduled destinations of a short.
+func (s *postgresURLStore) SetDestinations(ctx context.Context, shortID types.ShortId, destinations []DestinationModel) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("short_id = ?", shortID).Delete(&DestinationModel{}).Error; err != nil {
+			return err
+		}
+		if len(destinations) == 0 {
+			return nil
+		}
+		for i := range destinations {
+			destinations[i].ShortID = shortID
+		}
+		return tx.Create(&destinations).Error
+	})
+}
+
+// SetRules replaces the redirect rules of a short.
+func (s *postgresURLStore) SetRules(ctx context.Context, shortID types.ShortId, rules []RedirectRuleModel) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("short_id = ?", shortID).Delete(&RedirectRuleModel{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		for i := range rules {
+			rules[i].ShortID = shortID
+		}
+		return tx.Create(&rules).Error
+	})
+}
+
+// SetVariants replaces the A/B variants of a short. The new variants get new
+// ids, so clicks recorded for the old ones stay apart.
+func (s *postgresURLStore) SetVariants(ctx context.Context, shortID types.ShortId, variants []VariantModel) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("short_id = ?", shortID).Delete(&VariantModel{}).Error; err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		for i := range variants {
+			variants[i].ShortID = shortID
+		}
+		return tx.Create(&variants).Error
+	})
+}
+
+// SetBlockedReason blocks a short for reason, or unblocks it when reason is
+// empty.
+func (s *postgresURLStore) SetBlockedReason(ctx context.Context, shortID types.ShortId, reason string) error {
+	return s.db.WithContext(ctx).
+		Model(&ShortModel{}).
+		Where("id = ?", shortID).
+		UpdateColumn("blocked_reason", reason).Error
+}
+
+// SetMetadata stores the metadata fetched for a short's destination.
+func (s *postgresURLStore) SetMetadata(ctx context.Context, shortID types.ShortId, meta LinkMetadata) error {
+	return s.db.WithContext(ctx).
+		Model(&ShortModel{}).
+		Where("id = ?", shortID).
+		UpdateColumns(map[string]any{
+			"meta_title":       meta.Title,
+			"meta_description": meta.Description,
+			"meta_site_name":   meta.SiteName,
+			"meta_image_url":   meta.ImageUrl,
+			"meta_favicon_url": meta.FaviconUrl,
+			"meta_fetched_at":  meta.FetchedAt,
+		}).Error
+}
+
+// SetHealth stores the outcome of a health check of a short's destination.
+func (s *postgresURLStore) SetHealth(ctx context.Context, shortID types.ShortId, health LinkHealth) error {
+	return s.db.WithContext(ctx).
+		Model(&ShortModel{}).
+		Where("id = ?", shortID).
+		UpdateColumns(map[string]any{
+			"health_status":     health.Status,
+			"health_latency_ms": health.LatencyMs,
+			"health_error":      health.Error,
+			"health_failures":   health.Failures,
+			"health_broken":     health.Broken,
+			"health_checked_at": health.CheckedAt,
+		}).Error
+}
+
+// AddHistory appends entry to the short's history as its next version.
+func (s *postgresURLStore) AddHistory(ctx context.Context, entry ShortHistoryModel) (ShortHistoryModel, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&ShortHistoryModel{}).
+			Where("short_id = ?", entry.ShortID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		entry.Version = latest + 1
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return ShortHistoryModel{}, err
+	}
+	return entry, nil
+}
+
+// ListHistory returns the short's history, newest version first.
+func (s *postgresURLStore) ListHistory(ctx context.Context, shortID types.ShortId) ([]ShortHistoryModel, error) {
+	var entries []ShortHistoryModel
+	if err := s.db.WithContext(ctx).Where("short_id = ?", shortID).Order("version DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (s *postgresURLStore) GetHistoryVersion(ctx context.Context, shortID types.ShortId, version int) (ShortHistoryModel, error) {
+	var entry ShortHistoryModel
+	result := s.db.WithContext(ctx).Where("short_id = ? AND version = ?", shortID, version).First(&entry)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return ShortHistoryModel{}, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	if result.Error != nil {
+		return ShortHistoryModel{}, result.Error
+	}
+	return entry, nil
+}
+
+// ReleaseSlug records that a short URL was given up.
+func (s *postgresURLStore) ReleaseSlug(ctx context.Context, release ReleasedSlugModel) error {
+	if release.ReleasedAt.IsZero() {
+		release.ReleasedAt = time.Now()
+	}
+	return s.db.WithContext(ctx).Create(&release).Error
+}
+
+// GetRelease returns the latest release of a short URL since the given time,
+// or nil when it was not released since.
+func (s *postgresURLStore) GetRelease(ctx context.Context, domainID types.DomainId, shortUrl string, since time.Time) (*ReleasedSlugModel, error) {
+	var releases []ReleasedSlugModel
+	if err := s.db.WithContext(ctx).
+		Where("domain_id = ? AND short_url = ? AND released_at >= ?", domainID, shortUrl, since).
+		Order("released_at DESC").
+		Limit(1).
+		Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 {
+		return nil, nil
+	}
+	return &releases[0], nil
+}
+
+// DeleteReleases forgets the releases made before the given time.
+func (s *postgresURLStore) DeleteReleases(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).Where("released_at < ?", before).Delete(&ReleasedSlugModel{}).Error
+}
+
+func (s *postgresURLStore) ListTags(ctx context.Context, userID types.UserId) ([]TagModel, error) {
+	var tags []TagModel
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (s *postgresURLStore) CreateFolder(ctx context.Context, folder FolderModel) (FolderModel, error) {
+	result := s.db.WithContext(ctx).Create(&folder)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return FolderModel{}, fmt.Errorf("%w: %s", ErrFolderNameTaken, folder.Name)
+	}
+	if result.Error != nil {
+		return FolderModel{}, result.Error
+	}
+	return folder, nil
+}
+
+func (s *postgresURLStore) GetFolder(ctx context.Context, id types.FolderId) (FolderModel, error) {
+	var folder FolderModel
+	result := s.db.WithContext(ctx).First(&folder, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return FolderModel{}, fmt.Errorf("%w: %d", ErrFolderNotFound, id)
+	}
+	if result.Error != nil {
+		return FolderModel{}, result.Error
+	}
+	return folder, nil
+}
+
+func (s *postgresURLStore) ListFolders(ctx context.Context, userID types.UserId) ([]FolderModel, error) {
+	var folders []FolderModel
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// DeleteFolder removes a folder. Its shorts, including those in the trash,
+// are kept and no longer belong to any folder.
+func (s *postgresURLStore) DeleteFolder(ctx context.Context, id types.FolderId) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&ShortModel{}).Where("folder_id = ?", id).Update("folder_id", nil).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&FolderModel{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d", ErrFolderNotFound, id)
+		}
+		return nil
+	})
+}
+
+func (s *postgresURLStore) CreateCampaign(ctx context.Context, campaign CampaignModel) (CampaignModel, error) {
+	result := s.db.WithContext(ctx).Create(&campaign)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return CampaignModel{}, fmt.Errorf("%w: %s", ErrCampaignNameTaken, campaign.Name)
+	}
+	if result.Error != nil {
+		return CampaignModel{}, result.Error
+	}
+	return campaign, nil
+}
+
+func (s *postgresURLStore) GetCampaign(ctx context.Context, id types.CampaignId) (CampaignModel, error) {
+	var campaign CampaignModel
+	result := s.db.WithContext(ctx).First(&campaign, id)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return CampaignModel{}, fmt.Errorf("%w: %d", ErrCampaignNotFound, id)
+	}
+	if result.Error != nil {
+		return CampaignModel{}, result.Error
+	}
+	return campaign, nil
+}
+
+func (s *postgresURLStore) ListCampaigns(ctx context.Context, userID types.UserId) ([]CampaignModel, error) {
+	var campaigns []CampaignModel
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("name").Find(&campaigns).Error; err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// DeleteCampaign removes a campaign. Its shorts are kept, UTM parameters
+// included, and no longer belong to any campaign.
+func (s *postgresURLStore) DeleteCampaign(ctx context.Context, id types.CampaignId) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&ShortModel{}).Where("campaign_id = ?", id).Update("campaign_id", nil).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&CampaignModel{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d", ErrCampaignNotFound, id)
+		}
+		return nil
+	})
+}
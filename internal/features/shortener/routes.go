@@ -10,12 +10,49 @@ func RegisterRoutes(app *fiber.App, handler *ShortHandler) {
 	shorts := api.Group("/shorts", middleware.Authenticate())
 
 	shorts.Post("/", handler.Shorten)
+	shorts.Post("/bulk", handler.BulkShorten)
 
 	shorts.Get("/", middleware.Authorize("admin"), handler.GetAll)
+	shorts.Get("/export", middleware.Authorize("admin"), handler.Export)
+	shorts.Get("/trash", middleware.Authorize("admin"), handler.GetTrash)
 	shorts.Post("/search", middleware.Authorize("admin"), handler.Search)
 	shorts.Get("/user/:id/shorts", middleware.Authorize("admin"), handler.GetAllByUser)
+	shorts.Get("/:id/qr", handler.GetQRCode)
+	shorts.Get("/:id/history", handler.GetHistory)
+	shorts.Post("/:id/history/:version/rollback", handler.Rollback)
 	shorts.Get("/:id", middleware.Authorize("admin"), handler.GetById)
+	shorts.Patch("/:id", middleware.Authorize("admin"), handler.Update)
 	shorts.Delete("/:id", middleware.Authorize("admin"), handler.Delete)
+	shorts.Post("/:id/restore", middleware.Authorize("admin"), handler.Restore)
 
-	app.Get("/:url<[a-zA-Z0-9_-]+>", handler.RedirectToOriginalUrl)
+	me := api.Group("/me/shorts", middleware.Authenticate())
+	me.Get("/", handler.GetMine)
+	me.Post("/search", handler.SearchMine)
+	me.Post("/import", handler.ImportMine)
+	me.Get("/export", handler.ExportMine)
+	me.Get("/trash", handler.GetMyTrash)
+	me.Get("/:id", handler.GetMineById)
+	me.Patch("/:id", handler.UpdateMine)
+	me.Delete("/:id", handler.DeleteMine)
+	me.Post("/:id/restore", handler.RestoreMine)
+
+	folders := api.Group("/me/folders", middleware.Authenticate())
+	folders.Get("/", handler.GetFolders)
+	folders.Post("/", handler.CreateFolder)
+	folders.Delete("/:id", handler.DeleteFolder)
+
+	campaigns := api.Group("/me/campaigns", middleware.Authenticate())
+	campaigns.Get("/", handler.GetCampaigns)
+	campaigns.Post("/", handler.CreateCampaign)
+	campaigns.Get("/:id", handler.GetCampaign)
+	campaigns.Delete("/:id", handler.DeleteCampaign)
+
+	api.Get("/me/tags", middleware.Authenticate(), handler.GetTags)
+
+	// The optional trailing + asks for the preview page. The unlock form of
+	// a protected short posts back to the URL it was opened at, so the
+	// unlock route accepts it as well.
+	visitPath := "/:url<regex(^" + slugPattern + `\+?$)>`
+	app.Get(visitPath, handler.RedirectToOriginalUrl)
+	app.Post(visitPath, handler.UnlockShort)
 }
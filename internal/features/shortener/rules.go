@@ -0,0 +1,75 @@
+package shortener
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Kalmera74/Shorty/pkg/geoip"
+	"github.com/Kalmera74/Shorty/pkg/useragent"
+	"github.com/gofiber/fiber/v2"
+)
+
+// WithGeoLocator enables country conditions in redirect rules. Without a
+// locator no visitor has a country and such rules never match.
+func WithGeoLocator(locator geoip.ILocator) ShortServiceOption {
+	return func(s *shortService) {
+		s.Locator = locator
+	}
+}
+
+// VisitorCountry returns the country of ip, or "" when it is unknown.
+func (s *shortService) VisitorCountry(ip string) string {
+	if s.Locator == nil {
+		return ""
+	}
+	country, err := s.Locator.Country(ip)
+	if err != nil {
+		return ""
+	}
+	return country
+}
+
+// visitor describes the client of c for the redirect rules and A/B variants
+// of short. The country is only looked up when a rule asks for it.
+func (h *ShortHandler) visitor(c *fiber.Ctx, short ShortModel) Visitor {
+	info := useragent.Parse(c.Get(fiber.HeaderUserAgent))
+	visitor := Visitor{
+		OS:       info.OS,
+		Device:   info.Device,
+		Language: useragent.PreferredLanguage(c.Get(fiber.HeaderAcceptLanguage)),
+		Variant:  assignVariant(c, short),
+	}
+	if short.needsCountry() {
+		visitor.Country = h.service.VisitorCountry(c.IP())
+	}
+	return visitor
+}
+
+// validateRules rejects rules without any condition, which would shadow every
+// rule after them and the short's own destination.
+func validateRules(rules []RedirectRuleRequest) error {
+	for i, rule := range rules {
+		if rule.OS == "" && rule.Device == "" && rule.Language == "" && rule.Country == "" {
+			return fmt.Errorf("%w: rule %d has no condition", ErrInvalidRedirectRule, i)
+		}
+	}
+	return nil
+}
+
+func newRedirectRuleModels(rules []RedirectRuleRequest) []RedirectRuleModel {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	models := make([]RedirectRuleModel, 0, len(rules))
+	for _, rule := range rules {
+		models = append(models, RedirectRuleModel{
+			OS:       rule.OS,
+			Device:   rule.Device,
+			Language: strings.ToLower(rule.Language),
+			Country:  rule.Country,
+			Url:      rule.Url,
+		})
+	}
+	return models
+}
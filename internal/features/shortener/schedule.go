@@ -0,0 +1,71 @@
+package shortener
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotActiveError is returned when a short is visited before its activation
+// time. ComingSoonUrl is where the visitor should be sent meanwhile, empty
+// when the visit should end in a 404.
+type NotActiveError struct {
+	ShortUrl      string
+	ActivateAt    time.Time
+	ComingSoonUrl string
+}
+
+func (e *NotActiveError) Error() string {
+	return fmt.Sprintf("%v: %s until %s", ErrShortNotActive, e.ShortUrl, e.ActivateAt.Format(time.RFC3339))
+}
+
+func (e *NotActiveError) Unwrap() error {
+	return ErrShortNotActive
+}
+
+// WithComingSoonUrl sets where visitors of a short that is not active yet are
+// sent when the short has no coming soon URL of its own. Without one they get
+// a 404.
+func WithComingSoonUrl(url string) ShortServiceOption {
+	return func(s *shortService) {
+		s.ComingSoonUrl = url
+	}
+}
+
+func (s *shortService) comingSoonUrl(short ShortModel) string {
+	if short.ComingSoonUrl != "" {
+		return short.ComingSoonUrl
+	}
+	return s.ComingSoonUrl
+}
+
+// validateSchedule rejects an activation time at or after the expiry time and
+// destination windows that end before they start.
+func validateSchedule(activateAt, expiresAt *time.Time, destinations []DestinationRequest) error {
+	if activateAt != nil && expiresAt != nil && !activateAt.Before(*expiresAt) {
+		return fmt.Errorf("%w: activate_at must be before expires_at", ErrInvalidSchedule)
+	}
+
+	for i, destination := range destinations {
+		if destination.StartsAt != nil && destination.EndsAt != nil && !destination.StartsAt.Before(*destination.EndsAt) {
+			return fmt.Errorf("%w: destination %d ends before it starts", ErrInvalidSchedule, i)
+		}
+	}
+
+	return nil
+}
+
+func newDestinationModels(destinations []DestinationRequest) []DestinationModel {
+	if len(destinations) == 0 {
+		return nil
+	}
+
+	models := make([]DestinationModel, 0, len(destinations))
+	for _, destination := range destinations {
+		models = append(models, DestinationModel{
+			Url:      destination.Url,
+			StartsAt: destination.StartsAt,
+			EndsAt:   destination.EndsAt,
+		})
+	}
+	return models
+}
@@ -2,74 +2,283 @@ package shortener
 
 import (
 	"context"
-	"crypto/sha1"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
 	"time"
 
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	caching "github.com/Kalmera74/Shorty/pkg/cache"
+	"github.com/Kalmera74/Shorty/pkg/geoip"
+	"github.com/Kalmera74/Shorty/pkg/messaging"
+	"github.com/Kalmera74/Shorty/pkg/security"
 	"gorm.io/gorm"
 )
 
 type IShortService interface {
 	ShortenURL(ctx context.Context, req ShortenRequest) (ShortModel, error)
 	GetById(ctx context.Context, id types.ShortId) (ShortModel, error)
-	GetByShortUrl(ctx context.Context, shortUrl string) (ShortModel, error)
+	GetByShortUrl(ctx context.Context, domainID types.DomainId, shortUrl string) (ShortModel, error)
 	GetByLongUrl(ctx context.Context, originalUrl string) (ShortModel, error)
 	Search(ctx context.Context, req SearchRequest) ([]ShortModel, error)
 	GetAllByUser(ctx context.Context, userID types.UserId) ([]ShortModel, error)
-	GetAll(ctx context.Context, page, pageSize int) ([]ShortModel, int, error)
+	GetAll(ctx context.Context, params pagination.Params) ([]ShortModel, pagination.Page, error)
 	DeleteURL(ctx context.Context, shortID types.ShortId) error
+	Resolve(ctx context.Context, host, shortUrl string) (ShortModel, error)
+	ConsumeClick(ctx context.Context, short ShortModel) error
+	Unlock(ctx context.Context, short ShortModel, password string) error
+	UpdateShort(ctx context.Context, editor types.UserId, id types.ShortId, req UpdateShortRequest) (ShortModel, error)
+	GetByIdForUser(ctx context.Context, userID types.UserId, id types.ShortId) (ShortModel, error)
+	UpdateShortForUser(ctx context.Context, userID types.UserId, id types.ShortId, req UpdateShortRequest) (ShortModel, error)
+	DeleteURLForUser(ctx context.Context, userID types.UserId, id types.ShortId) error
+	GetTrash(ctx context.Context, userID *types.UserId) ([]ShortModel, error)
+	RestoreURL(ctx context.Context, id types.ShortId) (ShortModel, error)
+	RestoreURLForUser(ctx context.Context, userID types.UserId, id types.ShortId) (ShortModel, error)
+	PurgeTrash(ctx context.Context, retention time.Duration) (int, error)
+	BulkShorten(ctx context.Context, reqs []ShortenRequest, atomic bool) ([]BulkResult, error)
+	ImportCSV(ctx context.Context, userID types.UserId, r io.Reader) (ImportResult, error)
+	ExportCSV(ctx context.Context, userID *types.UserId, w io.Writer) error
+	QRCode(ctx context.Context, link string, req QRCodeRequest) ([]byte, error)
+	ShortLink(ctx context.Context, short ShortModel, baseURL string) string
+	GetTags(ctx context.Context, userID types.UserId) ([]TagModel, error)
+	CreateFolder(ctx context.Context, userID types.UserId, req FolderCreateRequest) (FolderModel, error)
+	GetFolders(ctx context.Context, userID types.UserId) ([]FolderModel, error)
+	DeleteFolder(ctx context.Context, userID types.UserId, id types.FolderId) error
+	CreateCampaign(ctx context.Context, userID types.UserId, req CampaignCreateRequest) (CampaignModel, error)
+	GetCampaigns(ctx context.Context, userID types.UserId) ([]CampaignModel, error)
+	GetCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) (CampaignModel, error)
+	DeleteCampaign(ctx context.Context, userID types.UserId, id types.CampaignId) error
+	VisitorCountry(ip string) string
+	EnforceBlocklist(ctx context.Context) (int, error)
+	CreatorName(ctx context.Context, short ShortModel) string
+	RefreshMetadata(ctx context.Context, job MetadataJob) error
+	CheckHealth(ctx context.Context) (HealthReport, error)
+	GetHistory(ctx context.Context, id types.ShortId) ([]ShortHistoryModel, error)
+	Rollback(ctx context.Context, editor types.UserId, id types.ShortId, version int) (ShortModel, error)
 }
+
+// BulkResult is the outcome of one item of a BulkShorten call. Err is nil when
+// Short was created or reused.
+type BulkResult struct {
+	Short ShortModel
+	Err   error
+}
+
+// DefaultBulkMaxItems caps the number of items a single bulk request may carry.
+const DefaultBulkMaxItems = 100
+
+const (
+	shortCacheTTL = time.Minute * 5
+
+	maxUnlockAttempts   = 5
+	unlockAttemptWindow = time.Minute * 15
+)
+
 type shortService struct {
-	Repository IShortRepository
-	Cacher     caching.ICacher
+	Repository   IShortRepository
+	Cacher       caching.ICacher
+	Generator    CodeGenerator
+	CodeRetries  int
+	BulkMaxItems int
+	Domains      IDomainLookup
+	// ComingSoonUrl is the fallback for shorts visited before activation.
+	ComingSoonUrl string
+	Locator       geoip.ILocator
+	Policy        *URLPolicy
+	Slugs         *SlugPolicy
+	Creators      ICreatorLookup
+	// Jobs receives a MetadataJob on MetadataQueue for new destinations.
+	Jobs          messaging.IMessaging
+	MetadataQueue string
+	Fetcher       IMetadataFetcher
+	Checker       ILinkChecker
+	Health        HealthConfig
+}
+
+// ShortServiceOption customises the service returned by NewShortService.
+type ShortServiceOption func(*shortService)
+
+// WithCodeGenerator replaces the default random code generator.
+func WithCodeGenerator(generator CodeGenerator) ShortServiceOption {
+	return func(s *shortService) {
+		s.Generator = generator
+	}
+}
+
+// WithCodeRetries sets how many candidate codes are tried before ShortenURL
+// gives up on a unique violation.
+func WithCodeRetries(retries int) ShortServiceOption {
+	return func(s *shortService) {
+		if retries > 0 {
+			s.CodeRetries = retries
+		}
+	}
 }
 
-func NewShortService(store IShortRepository, cacher caching.ICacher) IShortService {
-	return &shortService{
-		Repository: store,
-		Cacher:     cacher,
+// WithBulkMaxItems sets the largest batch BulkShorten accepts.
+func WithBulkMaxItems(max int) ShortServiceOption {
+	return func(s *shortService) {
+		if max > 0 {
+			s.BulkMaxItems = max
+		}
 	}
 }
 
+func NewShortService(store IShortRepository, cacher caching.ICacher, opts ...ShortServiceOption) IShortService {
+	s := &shortService{
+		Repository:   store,
+		Cacher:       cacher,
+		Generator:    NewRandomCodeGenerator(DefaultCodeLength),
+		CodeRetries:  DefaultCodeMaxRetries,
+		BulkMaxItems: DefaultBulkMaxItems,
+		Policy:       DefaultURLPolicy(),
+		Slugs:        DefaultSlugPolicy(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 func (s *shortService) ShortenURL(ctx context.Context, req ShortenRequest) (ShortModel, error) {
 
-	search := SearchRequest{
-		UserId:      &req.UserID,
-		OriginalUrl: &req.Url,
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return ShortModel{}, ErrInvalidExpiry
 	}
 
-	searchResult, err := s.Search(ctx, search)
-	if err == nil {
-		short := searchResult[0]
-		return short, nil
+	if err := validateSchedule(req.ActivateAt, req.ExpiresAt, req.Destinations); err != nil {
+		return ShortModel{}, err
 	}
 
-	var shortID string
+	if err := validateRules(req.Rules); err != nil {
+		return ShortModel{}, err
+	}
 
-	h := sha1.New()
-	h.Write([]byte(req.Url))
-	shortID = hex.EncodeToString(h.Sum(nil))[:8]
+	if err := validateVariants(req.Variants); err != nil {
+		return ShortModel{}, err
+	}
 
-	url := ShortModel{
-		UserID:      types.UserId(req.UserID),
-		OriginalUrl: req.Url,
-		ShortUrl:    shortID,
+	if err := s.checkURLs(ctx, req.targetURLs()); err != nil {
+		return ShortModel{}, err
 	}
 
-	short, err := s.Repository.Create(ctx, url)
+	if req.DomainID != 0 {
+		if s.Domains == nil {
+			return ShortModel{}, ErrDomainUnavailable
+		}
+		if err := s.Domains.CheckDomainUse(ctx, req.UserID, req.DomainID); err != nil {
+			return ShortModel{}, fmt.Errorf("%w: %v", ErrDomainUnavailable, err)
+		}
+	}
 
+	if req.FolderID != nil {
+		if _, err := s.folderForUser(ctx, req.UserID, *req.FolderID); err != nil {
+			return ShortModel{}, err
+		}
+	}
+
+	if req.CampaignID != nil {
+		campaign, err := s.campaignForUser(ctx, req.UserID, *req.CampaignID)
+		if err != nil {
+			return ShortModel{}, err
+		}
+		req.Url = mergeUTM(req.Url, campaign)
+	}
+
+	template, err := newShortModel(req)
 	if err != nil {
 		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
 	}
 
-	return short, nil
+	if len(req.Tags) > 0 {
+		template.Tags, err = s.Repository.EnsureTags(ctx, req.UserID, normalizeTags(req.Tags))
+		if err != nil {
+			return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+		}
+	}
+
+	if req.CustomShortUrl != nil {
+		return s.shortenWithCustomSlug(ctx, req, template)
+	}
+
+	// Only plain links are shared between identical requests, so a one-time
+	// or protected link never hands out an existing plain one or vice versa.
+	if !req.hasOptions() {
+		search := SearchRequest{
+			UserId:      &req.UserID,
+			OriginalUrl: &req.Url,
+			DomainID:    &req.DomainID,
+		}
+
+		searchResult, err := s.Search(ctx, search)
+		if err == nil {
+			for _, short := range searchResult {
+				if !short.hasOptions() && !short.Disabled {
+					return short, nil
+				}
+			}
+		}
+	}
+
+	for attempt := 0; attempt < s.CodeRetries; attempt++ {
+		code, err := s.Generator.Generate(ctx, req, attempt)
+		if err != nil {
+			return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+		}
+		if s.Slugs.checkWords(code) != nil {
+			continue
+		}
+
+		candidate := template
+		candidate.ShortUrl = code
+
+		short, err := s.Repository.Create(ctx, candidate)
+		if err == nil {
+			s.requestMetadata(short)
+			return short, nil
+		}
+		if !errors.Is(err, ErrShortUrlTaken) {
+			return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+		}
+	}
+
+	return ShortModel{}, fmt.Errorf("%w: gave up after %d attempts", ErrShortCodeExhausted, s.CodeRetries)
+}
+
+func (s *shortService) shortenWithCustomSlug(ctx context.Context, req ShortenRequest, template ShortModel) (ShortModel, error) {
+	slug := *req.CustomShortUrl
+	if err := s.checkCustomSlug(ctx, req.UserID, req.DomainID, slug); err != nil {
+		return ShortModel{}, err
+	}
+
+	existing, err := s.Repository.Search(ctx, SearchRequest{ShortUrl: &slug, DomainID: &req.DomainID})
+	if err != nil && !errors.Is(err, ErrShortNotFound) {
+		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+	}
+	if err == nil && len(existing) > 0 {
+		taken := existing[0]
+		if taken.UserID == req.UserID && taken.OriginalUrl == req.Url && !req.hasOptions() && !taken.hasOptions() {
+			return taken, nil
+		}
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortUrlTaken, slug)
+	}
+
+	template.ShortUrl = slug
+	short, err := s.Repository.Create(ctx, template)
+	if err != nil {
+		if errors.Is(err, ErrShortUrlTaken) {
+			return ShortModel{}, err
+		}
+		return ShortModel{}, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+	}
 
+	s.requestMetadata(short)
+	return short, nil
 }
+
 func (s *shortService) GetById(ctx context.Context, id types.ShortId) (ShortModel, error) {
 
 	short, err := s.Repository.GetById(ctx, id)
@@ -80,9 +289,9 @@ func (s *shortService) GetById(ctx context.Context, id types.ShortId) (ShortMode
 	return short, nil
 
 }
-func (s *shortService) GetByShortUrl(ctx context.Context, shortUrl string) (ShortModel, error) {
+func (s *shortService) GetByShortUrl(ctx context.Context, domainID types.DomainId, shortUrl string) (ShortModel, error) {
 
-	cachedShort, err := s.Cacher.Get(ctx, shortUrl)
+	cachedShort, err := s.Cacher.Get(ctx, shortByShortUrlKey(domainID, shortUrl))
 	if err == nil && cachedShort != "" {
 		unMarshalledShort := ShortModel{}
 		err := json.Unmarshal([]byte(cachedShort), &unMarshalledShort)
@@ -93,6 +302,7 @@ func (s *shortService) GetByShortUrl(ctx context.Context, shortUrl string) (Shor
 
 	search := SearchRequest{
 		ShortUrl: &shortUrl,
+		DomainID: &domainID,
 	}
 	result, err := s.Repository.Search(ctx, search)
 	if err != nil {
@@ -100,13 +310,70 @@ func (s *shortService) GetByShortUrl(ctx context.Context, shortUrl string) (Shor
 	}
 
 	short := result[0]
-	marshalledShort, err := json.Marshal(short)
-	if err == nil {
-		s.Cacher.Set(ctx, shortByShortUrlKey(shortUrl), marshalledShort, time.Minute*5)
+	if ttl := cacheTTL(short, time.Now()); ttl > 0 {
+		marshalledShort, err := json.Marshal(short)
+		if err == nil {
+			s.Cacher.Set(ctx, shortByShortUrlKey(domainID, shortUrl), marshalledShort, ttl)
+		}
 	}
 
 	return short, nil
 }
+
+// Resolve looks up a short for redirection by the requested host and short
+// URL, and rejects it when it has been disabled or blocked, its expiry time
+// has passed or its activation time has not come yet.
+func (s *shortService) Resolve(ctx context.Context, host, shortUrl string) (ShortModel, error) {
+	domainID, err := s.domainIDForHost(ctx, host)
+	if err != nil {
+		return ShortModel{}, err
+	}
+
+	short, err := s.GetByShortUrl(ctx, domainID, shortUrl)
+	if err != nil {
+		return ShortModel{}, err
+	}
+
+	if short.Disabled {
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortDisabled, shortUrl)
+	}
+
+	if short.BlockedReason != "" {
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortBlocked, shortUrl)
+	}
+
+	now := time.Now()
+	if short.IsExpired(now) {
+		return ShortModel{}, fmt.Errorf("%w: %s", ErrShortExpired, shortUrl)
+	}
+
+	if !short.IsActive(now) {
+		return ShortModel{}, &NotActiveError{
+			ShortUrl:      shortUrl,
+			ActivateAt:    *short.ActivateAt,
+			ComingSoonUrl: s.comingSoonUrl(short),
+		}
+	}
+
+	return short, nil
+}
+
+// ConsumeClick counts a visit against the short's click limit. Shorts without
+// a limit are not touched, keeping the redirect path free of writes.
+func (s *shortService) ConsumeClick(ctx context.Context, short ShortModel) error {
+	if short.MaxClicks == nil {
+		return nil
+	}
+
+	if err := s.Repository.ConsumeClick(ctx, short.ID); err != nil {
+		if errors.Is(err, ErrShortClickLimit) {
+			s.Cacher.Delete(ctx, shortByShortUrlKey(short.DomainID, short.ShortUrl))
+		}
+		return err
+	}
+
+	return nil
+}
 func (s *shortService) GetByLongUrl(ctx context.Context, originalUrl string) (ShortModel, error) {
 
 	search := SearchRequest{
@@ -146,15 +413,17 @@ func (s *shortService) GetAllByUser(ctx context.Context, userID types.UserId) ([
 
 	return result, nil
 }
-func (s *shortService) GetAll(ctx context.Context, page, pageSize int) ([]ShortModel, int, error) {
-	offset := (page - 1) * pageSize
-	shorts, total, err := s.Repository.GetAll(ctx, offset, pageSize)
+func (s *shortService) GetAll(ctx context.Context, params pagination.Params) ([]ShortModel, pagination.Page, error) {
+	shorts, page, err := s.Repository.GetAll(ctx, params)
 	if err != nil {
-		return nil, 0, err
+		return nil, pagination.Page{}, err
 	}
-	return shorts, total, nil
+	return shorts, page, nil
 }
 
+// DeleteURL moves a short to the trash. It stops resolving at once, but its
+// short URL stays reserved until the short is purged, so a restored short
+// gets its old link back and nobody else can take it over meanwhile.
 func (s *shortService) DeleteURL(ctx context.Context, shortID types.ShortId) error {
 
 	short, err := s.GetById(ctx, shortID)
@@ -165,11 +434,399 @@ func (s *shortService) DeleteURL(ctx context.Context, shortID types.ShortId) err
 		return err
 	}
 
-	s.Cacher.Delete(ctx, short.ShortUrl)
-	s.Cacher.Delete(ctx, short.OriginalUrl)
+	s.Cacher.Delete(ctx, shortByShortUrlKey(short.DomainID, short.ShortUrl))
 	return nil
 }
 
-func shortByShortUrlKey(shortUrl string) string {
-	return fmt.Sprintf("short:byShortUrl:%s", shortUrl)
+// UpdateShort retargets, renames, enables or disables, files, tags,
+// schedules or sets the redirect rules, A/B variants or fallback URL of a
+// short. The cache entries for the previous and the new short URL are
+// dropped together once the change is stored, so redirects pick it up
+// immediately.
+// The change is stored in a single transaction together with its history
+// entry, which records editor as the one who made it.
+func (s *shortService) UpdateShort(ctx context.Context, editor types.UserId, id types.ShortId, req UpdateShortRequest) (ShortModel, error) {
+	return s.updateShort(ctx, editor, id, req, nil)
+}
+
+func (s *shortService) updateShort(ctx context.Context, editor types.UserId, id types.ShortId, req UpdateShortRequest, rolledBackTo *int) (ShortModel, error) {
+	if req.OriginalUrl == nil && req.ShortUrl == nil && req.Enabled == nil &&
+		req.RedirectStatus == nil && req.ForwardQuery == nil && req.AlwaysPreview == nil && req.Tags == nil && req.FolderID == nil &&
+		req.ActivateAt == nil && req.ComingSoonUrl == nil && req.FallbackUrl == nil && req.Destinations == nil &&
+		req.Rules == nil && req.Variants == nil {
+		return ShortModel{}, ErrInvalidUpdateRequest
+	}
+
+	short, err := s.Repository.GetById(ctx, id)
+	if err != nil {
+		return ShortModel{}, err
+	}
+	previousShortUrl := short.ShortUrl
+	previousOriginalUrl := short.OriginalUrl
+	before := newShortSnapshot(short)
+
+	if req.OriginalUrl != nil {
+		short.OriginalUrl = *req.OriginalUrl
+	}
+	if req.Enabled != nil {
+		short.Disabled = !*req.Enabled
+	}
+	if req.RedirectStatus != nil {
+		short.RedirectStatus = *req.RedirectStatus
+	}
+	if req.ForwardQuery != nil {
+		short.ForwardQuery = *req.ForwardQuery
+	}
+	if req.AlwaysPreview != nil {
+		short.AlwaysPreview = *req.AlwaysPreview
+	}
+	if req.ActivateAt != nil {
+		short.ActivateAt = req.ActivateAt
+	}
+	if req.ComingSoonUrl != nil {
+		short.ComingSoonUrl = *req.ComingSoonUrl
+	}
+	if req.FallbackUrl != nil {
+		short.FallbackUrl = *req.FallbackUrl
+	}
+	if req.ActivateAt != nil || req.Destinations != nil {
+		var destinations []DestinationRequest
+		if req.Destinations != nil {
+			destinations = *req.Destinations
+		}
+		if err := validateSchedule(short.ActivateAt, short.ExpiresAt, destinations); err != nil {
+			return ShortModel{}, err
+		}
+	}
+	if req.Rules != nil {
+		if err := validateRules(*req.Rules); err != nil {
+			return ShortModel{}, err
+		}
+	}
+	if req.Variants != nil {
+		if err := validateVariants(*req.Variants); err != nil {
+			return ShortModel{}, err
+		}
+	}
+	if err := s.checkURLs(ctx, req.targetURLs()); err != nil {
+		return ShortModel{}, err
+	}
+	if req.ShortUrl != nil && *req.ShortUrl != previousShortUrl {
+		if err := s.ensureSlugAvailable(ctx, short.UserID, short.DomainID, *req.ShortUrl); err != nil {
+			return ShortModel{}, err
+		}
+		short.ShortUrl = *req.ShortUrl
+	}
+	if req.FolderID != nil {
+		if *req.FolderID == 0 {
+			short.FolderID = nil
+		} else {
+			if _, err := s.folderForUser(ctx, short.UserID, *req.FolderID); err != nil {
+				return ShortModel{}, err
+			}
+			short.FolderID = req.FolderID
+		}
+	}
+
+	// The short, its routing and its history entry are written together, so
+	// a failure halfway leaves neither a half-applied edit nor an edit that
+	// is missing from the history.
+	var updated ShortModel
+	err = s.Repository.Transaction(ctx, func(repo IShortRepository) error {
+		updated, err = repo.Update(ctx, short)
+		if err != nil {
+			return err
+		}
+
+		// The old short URL may still be printed somewhere, so nobody else
+		// gets it before the slug cooldown has passed.
+		if updated.ShortUrl != previousShortUrl {
+			release := ReleasedSlugModel{DomainID: short.DomainID, ShortUrl: previousShortUrl, UserID: short.UserID}
+			if err := repo.ReleaseSlug(ctx, release); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+		}
+
+		if req.Tags != nil {
+			tags, err := repo.EnsureTags(ctx, short.UserID, normalizeTags(*req.Tags))
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			if err := repo.SetTags(ctx, updated.ID, tags); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.Tags = tags
+		}
+
+		if req.Destinations != nil {
+			destinations := newDestinationModels(*req.Destinations)
+			if err := repo.SetDestinations(ctx, updated.ID, destinations); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.Destinations = destinations
+		}
+
+		if req.Rules != nil {
+			rules := newRedirectRuleModels(*req.Rules)
+			if err := repo.SetRules(ctx, updated.ID, rules); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.Rules = rules
+		}
+
+		if req.Variants != nil {
+			variants := newVariantModels(*req.Variants)
+			if err := repo.SetVariants(ctx, updated.ID, variants); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.Variants = variants
+		}
+
+		// A short blocked for its old destination is unblocked once it no
+		// longer points anywhere on the blocklist.
+		if updated.BlockedReason != "" && s.blockReason(updated) == "" {
+			if err := repo.SetBlockedReason(ctx, updated.ID, ""); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.BlockedReason = ""
+		}
+
+		// The health of the old destination says nothing about the new one.
+		if updated.OriginalUrl != previousOriginalUrl && updated.Health.CheckedAt != nil {
+			if err := repo.SetHealth(ctx, updated.ID, LinkHealth{}); err != nil {
+				return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+			}
+			updated.Health = LinkHealth{}
+		}
+
+		if err := recordChange(ctx, repo, editor, updated.ID, before, newShortSnapshot(updated), rolledBackTo); err != nil {
+			return fmt.Errorf("%w: %v", ErrShortUpdateFail, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ShortModel{}, err
+	}
+
+	if updated.OriginalUrl != previousOriginalUrl {
+		s.requestMetadata(updated)
+	}
+	s.Cacher.Delete(ctx, shortByShortUrlKey(short.DomainID, previousShortUrl), shortByShortUrlKey(short.DomainID, updated.ShortUrl))
+	return updated, nil
+}
+
+// GetByIdForUser returns the short only when it belongs to userID.
+func (s *shortService) GetByIdForUser(ctx context.Context, userID types.UserId, id types.ShortId) (ShortModel, error) {
+	short, err := s.Repository.GetById(ctx, id)
+	if err != nil {
+		return ShortModel{}, err
+	}
+
+	if short.UserID != userID {
+		return ShortModel{}, fmt.Errorf("%w: %d", ErrShortNotOwned, id)
+	}
+
+	return short, nil
+}
+
+func (s *shortService) UpdateShortForUser(ctx context.Context, userID types.UserId, id types.ShortId, req UpdateShortRequest) (ShortModel, error) {
+	if _, err := s.GetByIdForUser(ctx, userID, id); err != nil {
+		return ShortModel{}, err
+	}
+	return s.UpdateShort(ctx, userID, id, req)
+}
+
+func (s *shortService) DeleteURLForUser(ctx context.Context, userID types.UserId, id types.ShortId) error {
+	if _, err := s.GetByIdForUser(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.DeleteURL(ctx, id)
+}
+
+func (s *shortService) ensureSlugAvailable(ctx context.Context, owner types.UserId, domainID types.DomainId, slug string) error {
+	if err := s.checkCustomSlug(ctx, owner, domainID, slug); err != nil {
+		return err
+	}
+
+	existing, err := s.Repository.Search(ctx, SearchRequest{ShortUrl: &slug, DomainID: &domainID})
+	if err != nil && !errors.Is(err, ErrShortNotFound) {
+		return err
+	}
+	if err == nil && len(existing) > 0 {
+		return fmt.Errorf("%w: %s", ErrShortUrlTaken, slug)
+	}
+	return nil
+}
+
+// Unlock checks password against a protected short. Failed attempts are
+// counted per short and further attempts are refused once the limit is hit,
+// until the window expires.
+func (s *shortService) Unlock(ctx context.Context, short ShortModel, password string) error {
+	if !short.IsProtected() {
+		return nil
+	}
+
+	key := unlockAttemptsKey(short.ID)
+	if attempts, err := s.Cacher.Get(ctx, key); err == nil {
+		if n, _ := strconv.Atoi(attempts); n >= maxUnlockAttempts {
+			return ErrTooManyUnlockAttempts
+		}
+	}
+
+	if security.CheckPassword(password, short.PasswordHash) {
+		return nil
+	}
+
+	s.Cacher.Increment(ctx, key, unlockAttemptWindow)
+	return ErrInvalidShortPassword
+}
+
+// BulkShorten shortens every item of reqs. In best-effort mode each item
+// succeeds or fails on its own. In atomic mode the items share a transaction,
+// processing stops at the first failure and every other item is reported as
+// rolled back.
+func (s *shortService) BulkShorten(ctx context.Context, reqs []ShortenRequest, atomic bool) ([]BulkResult, error) {
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%w: no items", ErrInvalidShortenRequest)
+	}
+	if len(reqs) > s.BulkMaxItems {
+		return nil, fmt.Errorf("%w: %d items, at most %d allowed", ErrBulkTooLarge, len(reqs), s.BulkMaxItems)
+	}
+
+	if !atomic {
+		return s.shortenEach(ctx, reqs, false), nil
+	}
+
+	var results []BulkResult
+	err := s.Repository.Transaction(ctx, func(repo IShortRepository) error {
+		txService := *s
+		txService.Repository = repo
+		// Jobs are queued once the transaction commits, so the worker never
+		// sees a short that was rolled back.
+		txService.Jobs = nil
+
+		results = txService.shortenEach(ctx, reqs, true)
+		for _, result := range results {
+			if result.Err != nil {
+				return ErrBulkRolledBack
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		for _, result := range results {
+			if result.Short.Metadata.FetchedAt == nil {
+				s.requestMetadata(result.Short)
+			}
+		}
+		return results, nil
+	}
+	if !errors.Is(err, ErrBulkRolledBack) {
+		return nil, fmt.Errorf("%w: %v", ErrShortenFailed, err)
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = BulkResult{Err: ErrBulkRolledBack}
+		}
+	}
+	return results, nil
+}
+
+// shortenEach runs ShortenURL for every item. With stopOnError set, the items
+// after the first failure are not attempted and are reported as rolled back.
+func (s *shortService) shortenEach(ctx context.Context, reqs []ShortenRequest, stopOnError bool) []BulkResult {
+	results := make([]BulkResult, len(reqs))
+	failed := false
+
+	for i, req := range reqs {
+		if failed {
+			results[i] = BulkResult{Err: ErrBulkRolledBack}
+			continue
+		}
+
+		short, err := s.shortenOne(ctx, req)
+		results[i] = BulkResult{Short: short, Err: err}
+
+		failed = stopOnError && results[i].Err != nil
+	}
+
+	return results
+}
+
+// shortenOne validates req, which did not go through a handler, and shortens
+// it.
+func (s *shortService) shortenOne(ctx context.Context, req ShortenRequest) (ShortModel, error) {
+	if err := validate.Struct(req); err != nil {
+		return ShortModel{}, fmt.Errorf("%w: %v", ErrInvalidShortenRequest, err)
+	}
+	return s.ShortenURL(ctx, req)
+}
+
+// newShortModel builds the short described by req without its short code.
+func newShortModel(req ShortenRequest) (ShortModel, error) {
+	short := ShortModel{
+		UserID:         req.UserID,
+		OriginalUrl:    req.Url,
+		ExpiresAt:      req.ExpiresAt,
+		MaxClicks:      req.MaxClicks,
+		RedirectStatus: DefaultRedirectStatus,
+		ForwardQuery:   req.ForwardQuery,
+		AlwaysPreview:  req.AlwaysPreview,
+		DomainID:       req.DomainID,
+		FolderID:       req.FolderID,
+		CampaignID:     req.CampaignID,
+		ActivateAt:     req.ActivateAt,
+		Destinations:   newDestinationModels(req.Destinations),
+		Rules:          newRedirectRuleModels(req.Rules),
+		Variants:       newVariantModels(req.Variants),
+	}
+	if req.RedirectStatus != nil {
+		short.RedirectStatus = *req.RedirectStatus
+	}
+	if req.ComingSoonUrl != nil {
+		short.ComingSoonUrl = *req.ComingSoonUrl
+	}
+	if req.FallbackUrl != nil {
+		short.FallbackUrl = *req.FallbackUrl
+	}
+
+	if req.Password != nil {
+		hash, err := security.HashPassword(*req.Password)
+		if err != nil {
+			return ShortModel{}, err
+		}
+		short.PasswordHash = hash
+	}
+
+	return short, nil
+}
+
+// cacheTTL keeps a cached short from outliving its expiry time. A zero result
+// means the short must not be cached at all.
+func cacheTTL(short ShortModel, now time.Time) time.Duration {
+	ttl := shortCacheTTL
+	if short.ExpiresAt != nil {
+		if remaining := short.ExpiresAt.Sub(now); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// ShortCacheKey returns the key the short is cached under for redirects, for
+// features that change shorts without going through the service.
+func ShortCacheKey(short ShortModel) string {
+	return shortByShortUrlKey(short.DomainID, short.ShortUrl)
+}
+
+func shortByShortUrlKey(domainID types.DomainId, shortUrl string) string {
+	return fmt.Sprintf("short:byShortUrl:%d:%s", domainID, shortUrl)
+}
+
+func unlockAttemptsKey(id types.ShortId) string {
+	return fmt.Sprintf("short:unlockAttempts:%d", id)
 }
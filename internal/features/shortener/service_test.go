@@ -4,16 +4,116 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/Kalmera74/Shorty/pkg/linkcheck"
+	"github.com/Kalmera74/Shorty/pkg/messaging"
+	"github.com/Kalmera74/Shorty/pkg/metadata"
+	"github.com/Kalmera74/Shorty/pkg/security"
+	"github.com/Kalmera74/Shorty/pkg/useragent"
+	"github.com/Kalmera74/Shorty/pkg/wordlist"
 	"github.com/go-redis/redis/v8"
+	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
+type MockLocator struct {
+	mock.Mock
+}
+
+func (m *MockLocator) Country(ip string) (string, error) {
+	args := m.Called(ip)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLocator) Close() error {
+	return nil
+}
+
 // Mock Store //
+type MockScanner struct {
+	mock.Mock
+}
+
+func (m *MockScanner) Scan(ctx context.Context, url string) (ScanVerdict, error) {
+	args := m.Called(url)
+	return args.Get(0).(ScanVerdict), args.Error(1)
+}
+
+type stubHostResolver map[string][]net.IPAddr
+
+func (r stubHostResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+type stubCreators map[types.UserId]string
+
+func (c stubCreators) UserName(ctx context.Context, id types.UserId) (string, error) {
+	name, ok := c[id]
+	if !ok {
+		return "", errors.New("user not found")
+	}
+	return name, nil
+}
+
+type MockMessaging struct {
+	mock.Mock
+}
+
+func (m *MockMessaging) DeclareQueue(name string) error {
+	return nil
+}
+
+func (m *MockMessaging) Publish(queueName string, body []byte) error {
+	args := m.Called(queueName, string(body))
+	return args.Error(0)
+}
+
+func (m *MockMessaging) Consume(queueName, consumer string, autoAck bool) (<-chan messaging.IMessage, error) {
+	return nil, nil
+}
+
+func (m *MockMessaging) Close() {}
+
+type stubRoles map[types.UserId]string
+
+func (s stubRoles) UserRole(ctx context.Context, id types.UserId) (string, error) {
+	role, ok := s[id]
+	if !ok {
+		return "", errors.New("no such user")
+	}
+	return role, nil
+}
+
+type stubFetcher map[string]metadata.Metadata
+
+func (f stubFetcher) Fetch(ctx context.Context, url string) (metadata.Metadata, error) {
+	page, ok := f[url]
+	if !ok {
+		return metadata.Metadata{}, metadata.ErrUnexpectedStatus
+	}
+	return page, nil
+}
+
+type stubChecker map[string]linkcheck.Result
+
+func (c stubChecker) Check(ctx context.Context, url string) linkcheck.Result {
+	return c[url]
+}
+
 type MockStore struct {
 	mock.Mock
 }
@@ -31,14 +131,174 @@ func (m *MockStore) Search(ctx context.Context, search SearchRequest) ([]ShortMo
 	args := m.Called(search)
 	return args.Get(0).([]ShortModel), args.Error(1)
 }
-func (m *MockStore) GetAll(ctx context.Context, offset, limit int) ([]ShortModel, int, error) {
-	args := m.Called(ctx, offset, limit)
-	return args.Get(0).([]ShortModel), args.Int(1), args.Error(2)
+func (m *MockStore) GetAll(ctx context.Context, params pagination.Params) ([]ShortModel, pagination.Page, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]ShortModel), args.Get(1).(pagination.Page), args.Error(2)
 }
 func (m *MockStore) Delete(ctx context.Context, id types.ShortId) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
+func (m *MockStore) ConsumeClick(ctx context.Context, id types.ShortId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+func (m *MockStore) Update(ctx context.Context, short ShortModel) (ShortModel, error) {
+	args := m.Called(short)
+	return args.Get(0).(ShortModel), args.Error(1)
+}
+
+func (m *MockStore) Transaction(ctx context.Context, fn func(repo IShortRepository) error) error {
+	m.Called()
+	return fn(m)
+}
+
+func (m *MockStore) Iterate(ctx context.Context, userID *types.UserId, batchSize int, fn func(batch []ShortModel) error) error {
+	args := m.Called(userID, batchSize)
+	for _, batch := range args.Get(0).([][]ShortModel) {
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockStore) EnsureTags(ctx context.Context, userID types.UserId, names []string) ([]TagModel, error) {
+	args := m.Called(userID, names)
+	tags, _ := args.Get(0).([]TagModel)
+	return tags, args.Error(1)
+}
+
+func (m *MockStore) SetTags(ctx context.Context, shortID types.ShortId, tags []TagModel) error {
+	args := m.Called(shortID, tags)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetVariants(ctx context.Context, shortID types.ShortId, variants []VariantModel) error {
+	args := m.Called(shortID, variants)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetBlockedReason(ctx context.Context, shortID types.ShortId, reason string) error {
+	args := m.Called(shortID, reason)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetMetadata(ctx context.Context, shortID types.ShortId, meta LinkMetadata) error {
+	args := m.Called(shortID, meta)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetHealth(ctx context.Context, shortID types.ShortId, health LinkHealth) error {
+	args := m.Called(shortID, health)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListTrash(ctx context.Context, userID *types.UserId) ([]ShortModel, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]ShortModel), args.Error(1)
+}
+
+func (m *MockStore) GetDeleted(ctx context.Context, id types.ShortId) (ShortModel, error) {
+	args := m.Called(id)
+	return args.Get(0).(ShortModel), args.Error(1)
+}
+
+func (m *MockStore) Restore(ctx context.Context, id types.ShortId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	args := m.Called(deletedBefore)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) AddHistory(ctx context.Context, entry ShortHistoryModel) (ShortHistoryModel, error) {
+	args := m.Called(entry)
+	return args.Get(0).(ShortHistoryModel), args.Error(1)
+}
+
+func (m *MockStore) ListHistory(ctx context.Context, shortID types.ShortId) ([]ShortHistoryModel, error) {
+	args := m.Called(shortID)
+	return args.Get(0).([]ShortHistoryModel), args.Error(1)
+}
+
+func (m *MockStore) GetHistoryVersion(ctx context.Context, shortID types.ShortId, version int) (ShortHistoryModel, error) {
+	args := m.Called(shortID, version)
+	return args.Get(0).(ShortHistoryModel), args.Error(1)
+}
+
+func (m *MockStore) ReleaseSlug(ctx context.Context, release ReleasedSlugModel) error {
+	args := m.Called(release)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetRelease(ctx context.Context, domainID types.DomainId, shortUrl string, since time.Time) (*ReleasedSlugModel, error) {
+	args := m.Called(domainID, shortUrl, since)
+	release, _ := args.Get(0).(*ReleasedSlugModel)
+	return release, args.Error(1)
+}
+
+func (m *MockStore) DeleteReleases(ctx context.Context, before time.Time) error {
+	args := m.Called(before)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetRules(ctx context.Context, shortID types.ShortId, rules []RedirectRuleModel) error {
+	args := m.Called(shortID, rules)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetDestinations(ctx context.Context, shortID types.ShortId, destinations []DestinationModel) error {
+	args := m.Called(shortID, destinations)
+	return args.Error(0)
+}
+
+func (m *MockStore) ListTags(ctx context.Context, userID types.UserId) ([]TagModel, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]TagModel), args.Error(1)
+}
+
+func (m *MockStore) CreateFolder(ctx context.Context, folder FolderModel) (FolderModel, error) {
+	args := m.Called(folder)
+	return args.Get(0).(FolderModel), args.Error(1)
+}
+
+func (m *MockStore) CreateCampaign(ctx context.Context, campaign CampaignModel) (CampaignModel, error) {
+	args := m.Called(campaign)
+	return args.Get(0).(CampaignModel), args.Error(1)
+}
+
+func (m *MockStore) GetCampaign(ctx context.Context, id types.CampaignId) (CampaignModel, error) {
+	args := m.Called(id)
+	return args.Get(0).(CampaignModel), args.Error(1)
+}
+
+func (m *MockStore) ListCampaigns(ctx context.Context, userID types.UserId) ([]CampaignModel, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]CampaignModel), args.Error(1)
+}
+
+func (m *MockStore) DeleteCampaign(ctx context.Context, id types.CampaignId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetFolder(ctx context.Context, id types.FolderId) (FolderModel, error) {
+	args := m.Called(id)
+	return args.Get(0).(FolderModel), args.Error(1)
+}
+
+func (m *MockStore) ListFolders(ctx context.Context, userID types.UserId) ([]FolderModel, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]FolderModel), args.Error(1)
+}
+
+func (m *MockStore) DeleteFolder(ctx context.Context, id types.FolderId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
 
 // Mock Redis //
 type MockRedis struct {
@@ -57,11 +317,47 @@ func (m *MockRedis) Set(ctx context.Context, key string, value interface{}, ttl
 	return nil
 }
 
-func (m *MockRedis) Delete(ctx context.Context, key string) error {
-	m.Called(ctx, key)
+func (m *MockRedis) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.Called(ctx, key)
+	}
 	return nil
 }
 
+func (m *MockRedis) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(ctx, key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// Mock Domains //
+type MockDomains struct {
+	mock.Mock
+}
+
+func (m *MockDomains) DomainIDForHost(ctx context.Context, host string) (types.DomainId, error) {
+	args := m.Called(host)
+	return args.Get(0).(types.DomainId), args.Error(1)
+}
+
+func (m *MockDomains) HostForDomain(ctx context.Context, id types.DomainId) (string, error) {
+	args := m.Called(id)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDomains) CheckDomainUse(ctx context.Context, userID types.UserId, id types.DomainId) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+// Stub Generator //
+type sequenceGenerator struct {
+	codes []string
+}
+
+func (g *sequenceGenerator) Generate(ctx context.Context, req ShortenRequest, attempt int) (string, error) {
+	return g.codes[attempt%len(g.codes)], nil
+}
+
 // ShortenURL Tests //
 func TestShortenURL_Hit_DB(t *testing.T) {
 	mockStore := new(MockStore)
@@ -118,6 +414,212 @@ func TestShortURL_Miss_DB(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestShortenURL_RetriesOnCollision(t *testing.T) {
+	mockStore := new(MockStore)
+
+	req := ShortenRequest{UserID: 2, Url: "https://example.com"}
+	expectedShort := ShortModel{ID: 9, UserID: 2, OriginalUrl: req.Url, ShortUrl: "second"}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", ShortModel{UserID: 2, OriginalUrl: req.Url, ShortUrl: "first", RedirectStatus: DefaultRedirectStatus}).Return(ShortModel{}, ErrShortUrlTaken).Once()
+	mockStore.On("Create", ShortModel{UserID: 2, OriginalUrl: req.Url, ShortUrl: "second", RedirectStatus: DefaultRedirectStatus}).Return(expectedShort, nil).Once()
+
+	service := NewShortService(mockStore, nil, WithCodeGenerator(&sequenceGenerator{codes: []string{"first", "second"}}))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedShort, result)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_GivesUpAfterRetries(t *testing.T) {
+	mockStore := new(MockStore)
+
+	req := ShortenRequest{UserID: 2, Url: "https://example.com"}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(ShortModel{}, ErrShortUrlTaken)
+
+	service := NewShortService(mockStore, nil,
+		WithCodeGenerator(&sequenceGenerator{codes: []string{"taken"}}),
+		WithCodeRetries(3),
+	)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortCodeExhausted)
+	mockStore.AssertNumberOfCalls(t, "Create", 3)
+}
+
+func TestShortenURL_CreateFailureIsNotRetried(t *testing.T) {
+	mockStore := new(MockStore)
+
+	req := ShortenRequest{UserID: 2, Url: "https://example.com"}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(ShortModel{}, errors.New("connection reset"))
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortenFailed)
+	mockStore.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestShortenURL_CustomSlug_Created(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "my-link_1"
+	req := ShortenRequest{
+		UserID:         1,
+		Url:            "https://example.com",
+		CustomShortUrl: &slug,
+	}
+
+	expectedShort := ShortModel{
+		ID:          1,
+		UserID:      1,
+		OriginalUrl: "https://example.com",
+		ShortUrl:    slug,
+	}
+
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", ShortModel{UserID: 1, OriginalUrl: "https://example.com", ShortUrl: slug, RedirectStatus: DefaultRedirectStatus}).Return(expectedShort, nil)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedShort, result)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_CustomSlug_Invalid(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "not/valid"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrInvalidCustomShortUrl)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlug_Reserved(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "Swagger"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrReservedShortUrl)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlug_Taken(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "promo"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{
+		{ID: 7, UserID: 2, OriginalUrl: "https://other.com", ShortUrl: slug},
+	}, nil)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortUrlTaken)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlug_TakenOnInsert(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "promo"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(ShortModel{}, ErrShortUrlTaken)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortUrlTaken)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_ExpiryInPast(t *testing.T) {
+	mockStore := new(MockStore)
+
+	past := time.Now().Add(-time.Hour)
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", ExpiresAt: &past}
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+func TestShortenURL_WithLimits_DoesNotReuseExisting(t *testing.T) {
+	mockStore := new(MockStore)
+
+	maxClicks := 1
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", MaxClicks: &maxClicks}
+	expectedShort := ShortModel{ID: 2, UserID: 1, OriginalUrl: req.Url, ShortUrl: "oneshot", MaxClicks: &maxClicks}
+
+	mockStore.On("Create", ShortModel{UserID: 1, OriginalUrl: req.Url, ShortUrl: "oneshot", MaxClicks: &maxClicks, RedirectStatus: DefaultRedirectStatus}).Return(expectedShort, nil)
+
+	service := NewShortService(mockStore, nil, WithCodeGenerator(&sequenceGenerator{codes: []string{"oneshot"}}))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedShort, result)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+func TestShortenURL_RedirectOptions(t *testing.T) {
+	mockStore := new(MockStore)
+
+	status := 308
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", RedirectStatus: &status, ForwardQuery: true}
+
+	mockStore.On("Create", mock.MatchedBy(func(short ShortModel) bool {
+		return short.RedirectStatus == 308 && short.ForwardQuery
+	})).Return(ShortModel{ID: 3, RedirectStatus: 308, ForwardQuery: true}, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 308, result.RedirectStatus)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+// Destination Tests
+func TestRedirectStatus_DefaultsTo302(t *testing.T) {
+	assert.Equal(t, 302, redirectStatus(ShortModel{}))
+	assert.Equal(t, 302, redirectStatus(ShortModel{RedirectStatus: 200}))
+	assert.Equal(t, 307, redirectStatus(ShortModel{RedirectStatus: 307}))
+}
+
+func TestMergeQuery(t *testing.T) {
+	assert.Equal(t, "https://example.com/landing", mergeQuery("https://example.com/landing", ""))
+	assert.Equal(t,
+		"https://example.com/landing?utm_source=mail",
+		mergeQuery("https://example.com/landing", "utm_source=mail"))
+	assert.Equal(t,
+		"https://example.com/landing?lang=en&utm_source=mail#top",
+		mergeQuery("https://example.com/landing?lang=en&utm_source=site#top", "utm_source=mail"))
+}
+
 // GetById Tests
 func TestGetById_ValidId(t *testing.T) {
 	mockStore := new(MockStore)
@@ -166,7 +668,7 @@ func TestGetByShortUrl_EmptyUrl(t *testing.T) {
 	mockRedis.On("Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", ErrShortNotFound)
 	mockStore.On("Search", mock.Anything, mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
 
-	_, err := service.GetByShortUrl(nil, "")
+	_, err := service.GetByShortUrl(nil, 0, "")
 
 	assert.Error(t, err)
 }
@@ -183,11 +685,11 @@ func TestGetByShortUrl_CacheHit(t *testing.T) {
 	}
 	marshalledShort, _ := json.Marshal(expectedShort)
 
-	mockRedis.On("Get", mock.Anything, expectedShort.ShortUrl).Return(string(marshalledShort), nil)
+	mockRedis.On("Get", mock.Anything, shortByShortUrlKey(0, expectedShort.ShortUrl)).Return(string(marshalledShort), nil)
 	mockStore.AssertNotCalled(t, "GetByShortUrl")
 
 	service := NewShortService(mockStore, mockRedis)
-	result, err := service.GetByShortUrl(nil, expectedShort.ShortUrl)
+	result, err := service.GetByShortUrl(nil, 0, expectedShort.ShortUrl)
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedShort, result)
@@ -212,7 +714,7 @@ func TestGetByShortUrl_CacheMiss_DBHit(t *testing.T) {
 	mockStore.On("Search", mock.Anything, mock.Anything).Return(expectedShort, nil)
 
 	service := NewShortService(mockStore, mockRedis)
-	result, err := service.GetByShortUrl(nil, "")
+	result, err := service.GetByShortUrl(nil, 0, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, expectedShort[0], result)
@@ -228,14 +730,312 @@ func TestGetByShortUrl_CacheMiss_DBMiss(t *testing.T) {
 	mockStore.On("Search", mock.Anything, mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
 
 	service := NewShortService(mockStore, mockRedis)
-	_, err := service.GetByShortUrl(nil, "nonexistent")
+	_, err := service.GetByShortUrl(nil, 0, "nonexistent")
 
 	assert.Error(t, err)
 	mockStore.AssertExpectations(t)
 	mockRedis.AssertExpectations(t)
 }
 
-// GetByLongUrl Tests
+func TestGetByShortUrl_CacheTTLCappedByExpiry(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	expiresAt := time.Now().Add(time.Minute)
+	expectedShort := []ShortModel{{ID: 1, ShortUrl: "soon", ExpiresAt: &expiresAt}}
+
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, shortByShortUrlKey(0, "soon"), mock.Anything, mock.MatchedBy(func(ttl time.Duration) bool {
+		return ttl > 0 && ttl <= time.Minute
+	})).Return(nil)
+	mockStore.On("Search", mock.Anything).Return(expectedShort, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.GetByShortUrl(nil, 0, "soon")
+
+	assert.NoError(t, err)
+	mockRedis.AssertExpectations(t)
+}
+
+// Resolve Tests
+func TestResolve_Expired(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	expiredAt := time.Now().Add(-time.Minute)
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{{ID: 1, ShortUrl: "old", ExpiresAt: &expiredAt}}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.Resolve(nil, "", "old")
+
+	assert.ErrorIs(t, err, ErrShortExpired)
+	mockRedis.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolve_NotExpired(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	expiresAt := time.Now().Add(time.Hour)
+	expectedShort := ShortModel{ID: 1, ShortUrl: "fresh", ExpiresAt: &expiresAt}
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{expectedShort}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	result, err := service.Resolve(nil, "", "fresh")
+
+	assert.NoError(t, err)
+	assert.Equal(t, expectedShort.ID, result.ID)
+}
+
+// ConsumeClick Tests
+func TestConsumeClick_NoLimit(t *testing.T) {
+	mockStore := new(MockStore)
+
+	service := NewShortService(mockStore, nil)
+	err := service.ConsumeClick(nil, ShortModel{ID: 1})
+
+	assert.NoError(t, err)
+	mockStore.AssertNotCalled(t, "ConsumeClick", mock.Anything)
+}
+
+func TestConsumeClick_LimitReached(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	maxClicks := 3
+	mockStore.On("ConsumeClick", types.ShortId(1)).Return(ErrShortClickLimit)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "limited")).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	err := service.ConsumeClick(nil, ShortModel{ID: 1, ShortUrl: "limited", MaxClicks: &maxClicks})
+
+	assert.ErrorIs(t, err, ErrShortClickLimit)
+	mockRedis.AssertExpectations(t)
+}
+
+// Unlock Tests
+func TestShortenURL_PasswordIsHashed(t *testing.T) {
+	mockStore := new(MockStore)
+
+	password := "s3cret"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", Password: &password}
+
+	mockStore.On("Create", mock.MatchedBy(func(short ShortModel) bool {
+		return short.PasswordHash != "" && short.PasswordHash != password &&
+			security.CheckPassword(password, short.PasswordHash)
+	})).Return(ShortModel{ID: 1}, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	mockStore.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+func TestUnlock_CorrectPassword(t *testing.T) {
+	mockRedis := new(MockRedis)
+
+	hash, _ := security.HashPassword("s3cret")
+	mockRedis.On("Get", mock.Anything, unlockAttemptsKey(1)).Return("", redis.Nil)
+
+	service := NewShortService(new(MockStore), mockRedis)
+	err := service.Unlock(nil, ShortModel{ID: 1, PasswordHash: hash}, "s3cret")
+
+	assert.NoError(t, err)
+	mockRedis.AssertNotCalled(t, "Increment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUnlock_WrongPasswordCountsAttempt(t *testing.T) {
+	mockRedis := new(MockRedis)
+
+	hash, _ := security.HashPassword("s3cret")
+	mockRedis.On("Get", mock.Anything, unlockAttemptsKey(1)).Return("1", nil)
+	mockRedis.On("Increment", mock.Anything, unlockAttemptsKey(1), unlockAttemptWindow).Return(int64(2), nil)
+
+	service := NewShortService(new(MockStore), mockRedis)
+	err := service.Unlock(nil, ShortModel{ID: 1, PasswordHash: hash}, "guess")
+
+	assert.ErrorIs(t, err, ErrInvalidShortPassword)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestUnlock_TooManyAttempts(t *testing.T) {
+	mockRedis := new(MockRedis)
+
+	hash, _ := security.HashPassword("s3cret")
+	mockRedis.On("Get", mock.Anything, unlockAttemptsKey(1)).Return("5", nil)
+
+	service := NewShortService(new(MockStore), mockRedis)
+	err := service.Unlock(nil, ShortModel{ID: 1, PasswordHash: hash}, "s3cret")
+
+	assert.ErrorIs(t, err, ErrTooManyUnlockAttempts)
+	mockRedis.AssertNotCalled(t, "Increment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResolve_Disabled(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{{ID: 1, ShortUrl: "off", Disabled: true}}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.Resolve(nil, "", "off")
+
+	assert.ErrorIs(t, err, ErrShortDisabled)
+}
+
+// UpdateShort Tests
+func TestUpdateShort_RetargetAndRename(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	newUrl := "https://fixed.example.com"
+	newSlug := "fixed"
+	existing := ShortModel{ID: 1, UserID: 1, OriginalUrl: "https://typo.example.com", ShortUrl: "old"}
+	expected := ShortModel{ID: 1, UserID: 1, OriginalUrl: newUrl, ShortUrl: newSlug}
+
+	mockStore.On("GetById", types.ShortId(1)).Return(existing, nil)
+	mockStore.On("Search", SearchRequest{ShortUrl: &newSlug, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", expected).Return(expected, nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "old")).Return(nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "fixed")).Return(nil)
+	mockStore.On("AddHistory", mock.MatchedBy(func(entry ShortHistoryModel) bool {
+		return entry.ShortID == 1 && entry.EditorID == 2 && entry.RolledBackTo == nil &&
+			entry.Old.OriginalUrl == "https://typo.example.com" && entry.Old.ShortUrl == "old" &&
+			entry.New.OriginalUrl == newUrl && entry.New.ShortUrl == newSlug
+	})).Return(ShortHistoryModel{Version: 1}, nil).Once()
+	mockStore.On("GetRelease", types.DomainId(0), newSlug, mock.Anything).Return(nil, nil)
+	mockStore.On("ReleaseSlug", ReleasedSlugModel{ShortUrl: "old", UserID: 1}).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	result, err := service.UpdateShort(nil, 2, 1, UpdateShortRequest{OriginalUrl: &newUrl, ShortUrl: &newSlug})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStore.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestUpdateShort_Disable(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	enabled := false
+	existing := ShortModel{ID: 1, ShortUrl: "abc"}
+
+	mockStore.On("GetById", types.ShortId(1)).Return(existing, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", ShortModel{ID: 1, ShortUrl: "abc", Disabled: true}).Return(ShortModel{ID: 1, ShortUrl: "abc", Disabled: true}, nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	result, err := service.UpdateShort(nil, 2, 1, UpdateShortRequest{Enabled: &enabled})
+
+	assert.NoError(t, err)
+	assert.True(t, result.Disabled)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+func TestUpdateShort_HistoryFailureRollsBack(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	tags := []string{"sale"}
+	existing := ShortModel{ID: 1, UserID: 2, ShortUrl: "abc"}
+	saleTag := TagModel{ID: 4, UserID: 2, Name: "sale"}
+
+	mockStore.On("GetById", types.ShortId(1)).Return(existing, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", existing).Return(existing, nil)
+	mockStore.On("EnsureTags", types.UserId(2), tags).Return([]TagModel{saleTag}, nil)
+	mockStore.On("SetTags", types.ShortId(1), []TagModel{saleTag}).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, errors.New("connection reset"))
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.UpdateShort(nil, 2, 1, UpdateShortRequest{Tags: &tags})
+
+	assert.ErrorIs(t, err, ErrShortUpdateFail)
+	mockStore.AssertNumberOfCalls(t, "Transaction", 1)
+	mockRedis.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUpdateShort_SlugTaken(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "taken"
+	mockStore.On("GetById", types.ShortId(1)).Return(ShortModel{ID: 1, ShortUrl: "mine"}, nil)
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{{ID: 2, ShortUrl: slug}}, nil)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.UpdateShort(nil, 2, 1, UpdateShortRequest{ShortUrl: &slug})
+
+	assert.ErrorIs(t, err, ErrShortUrlTaken)
+	mockStore.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestUpdateShort_Empty(t *testing.T) {
+	service := NewShortService(new(MockStore), nil)
+	_, err := service.UpdateShort(nil, 2, 1, UpdateShortRequest{})
+
+	assert.ErrorIs(t, err, ErrInvalidUpdateRequest)
+}
+
+// Ownership Tests
+func TestGetByIdForUser_Owner(t *testing.T) {
+	mockStore := new(MockStore)
+	expected := ShortModel{ID: 1, UserID: 5, ShortUrl: "mine"}
+	mockStore.On("GetById", types.ShortId(1)).Return(expected, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.GetByIdForUser(nil, 5, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func TestGetByIdForUser_NotOwner(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("GetById", types.ShortId(1)).Return(ShortModel{ID: 1, UserID: 5}, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.GetByIdForUser(nil, 6, 1)
+
+	assert.ErrorIs(t, err, ErrShortNotOwned)
+}
+
+func TestDeleteURLForUser_NotOwner(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("GetById", types.ShortId(1)).Return(ShortModel{ID: 1, UserID: 5}, nil)
+
+	service := NewShortService(mockStore, new(MockRedis))
+	err := service.DeleteURLForUser(nil, 6, 1)
+
+	assert.ErrorIs(t, err, ErrShortNotOwned)
+	mockStore.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestUpdateShortForUser_NotOwner(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("GetById", types.ShortId(1)).Return(ShortModel{ID: 1, UserID: 5}, nil)
+
+	newUrl := "https://example.com"
+	service := NewShortService(mockStore, nil)
+	_, err := service.UpdateShortForUser(nil, 6, 1, UpdateShortRequest{OriginalUrl: &newUrl})
+
+	assert.ErrorIs(t, err, ErrShortNotOwned)
+	mockStore.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+// GetByLongUrl Tests
 func TestGetByLongUrl_ValidUrl_Hit(t *testing.T) {
 	mockStore := new(MockStore)
 	service := NewShortService(mockStore, nil)
@@ -361,19 +1161,24 @@ func TestGetAllURLs_Found(t *testing.T) {
 	service := NewShortService(mockStore, nil)
 	expectedShorts := []ShortModel{{ID: 1}, {ID: 2}}
 
-	mockStore.On("GetAll", mock.Anything, mock.Anything, mock.Anything).Return(expectedShorts, 1, nil)
-	result, _, err := service.GetAll(nil, 1, 1)
+	total := 2
+	params := pagination.Params{After: 3, Limit: 2, CountTotal: true}
+	expectedPage := pagination.Page{NextCursor: pagination.EncodeCursor(1), Total: &total}
+
+	mockStore.On("GetAll", mock.Anything, params).Return(expectedShorts, expectedPage, nil)
+	result, page, err := service.GetAll(nil, params)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedShorts, result)
+	assert.Equal(t, expectedPage, page)
 	mockStore.AssertExpectations(t)
 }
 
 func TestGetAllURLs_StoreError(t *testing.T) {
 	mockStore := new(MockStore)
 	service := NewShortService(mockStore, nil)
-	mockStore.On("GetAll", mock.Anything, mock.Anything, mock.Anything).Return([]ShortModel(nil), -1, errors.New("database error"))
+	mockStore.On("GetAll", mock.Anything, mock.Anything).Return([]ShortModel(nil), pagination.Page{}, errors.New("database error"))
 
-	_, _, err := service.GetAll(nil, 1, 1)
+	_, _, err := service.GetAll(nil, pagination.Params{Limit: 10})
 	assert.Error(t, err)
 	assert.EqualError(t, err, "database error")
 	mockStore.AssertExpectations(t)
@@ -416,3 +1221,1252 @@ func TestDeleteURL_StoreError(t *testing.T) {
 	assert.EqualError(t, err, "database delete error")
 	mockStore.AssertExpectations(t)
 }
+
+// BulkShorten Tests //
+func TestBulkShorten_BestEffort_ReportsEachItem(t *testing.T) {
+	mockStore := new(MockStore)
+	first, taken := "first", "taken"
+
+	reqs := []ShortenRequest{
+		{UserID: 1, Url: "https://example.com/a", CustomShortUrl: &first},
+		{UserID: 1, Url: "not a url"},
+		{UserID: 1, Url: "https://example.com/b", CustomShortUrl: &taken},
+	}
+	created := ShortModel{ID: 1, UserID: 1, OriginalUrl: "https://example.com/a", ShortUrl: "first"}
+
+	mockStore.On("Search", SearchRequest{ShortUrl: &first, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Search", SearchRequest{ShortUrl: &taken, DomainID: new(types.DomainId)}).Return([]ShortModel{{ID: 9, UserID: 2, ShortUrl: "taken"}}, nil)
+	mockStore.On("Create", mock.Anything).Return(created, nil).Once()
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	results, err := service.BulkShorten(nil, reqs, false)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, created, results[0].Short)
+	assert.Equal(t, "invalid_request", ErrorCode(results[1].Err))
+	assert.Equal(t, "short_url_taken", ErrorCode(results[2].Err))
+	mockStore.AssertNotCalled(t, "Transaction")
+}
+
+func TestBulkShorten_Atomic_RollsBackOnFailure(t *testing.T) {
+	mockStore := new(MockStore)
+	first, taken, third := "first", "taken", "third"
+
+	reqs := []ShortenRequest{
+		{UserID: 1, Url: "https://example.com/a", CustomShortUrl: &first},
+		{UserID: 1, Url: "https://example.com/b", CustomShortUrl: &taken},
+		{UserID: 1, Url: "https://example.com/c", CustomShortUrl: &third},
+	}
+
+	mockStore.On("Transaction").Return()
+	mockStore.On("Search", SearchRequest{ShortUrl: &first, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Search", SearchRequest{ShortUrl: &taken, DomainID: new(types.DomainId)}).Return([]ShortModel{{ID: 9, UserID: 2, ShortUrl: "taken"}}, nil)
+	mockStore.On("Create", mock.Anything).Return(ShortModel{ID: 1, ShortUrl: "first"}, nil).Once()
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	results, err := service.BulkShorten(nil, reqs, true)
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, results[0].Err, ErrBulkRolledBack)
+	assert.Equal(t, ShortModel{}, results[0].Short)
+	assert.ErrorIs(t, results[1].Err, ErrShortUrlTaken)
+	assert.ErrorIs(t, results[2].Err, ErrBulkRolledBack)
+	mockStore.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestBulkShorten_TooManyItems(t *testing.T) {
+	mockStore := new(MockStore)
+
+	reqs := make([]ShortenRequest, 3)
+
+	service := NewShortService(mockStore, nil, WithBulkMaxItems(2))
+	_, err := service.BulkShorten(nil, reqs, false)
+
+	assert.ErrorIs(t, err, ErrBulkTooLarge)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+// CSV Tests //
+func TestImportCSV_ReportsRowErrors(t *testing.T) {
+	mockStore := new(MockStore)
+	slug := "promo"
+
+	file := strings.NewReader("original_url,custom_short_url,expires_at,tags\n" +
+		"https://example.com/a,promo,,news\n" +
+		"https://example.com/b,,next tuesday,\n" +
+		"not a url,,,\n")
+
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	news := []TagModel{{ID: 7, UserID: 3, Name: "news"}}
+	mockStore.On("EnsureTags", types.UserId(3), []string{"news"}).Return(news, nil)
+	mockStore.On("Create", ShortModel{UserID: 3, OriginalUrl: "https://example.com/a", ShortUrl: "promo", RedirectStatus: DefaultRedirectStatus, Tags: news}).
+		Return(ShortModel{ID: 1, UserID: 3, OriginalUrl: "https://example.com/a", ShortUrl: "promo"}, nil)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.ImportCSV(nil, 3, file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 2, result.Failed)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "invalid_row", result.Errors[0].Code)
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "invalid_request", result.Errors[1].Code)
+	mockStore.AssertExpectations(t)
+}
+
+func TestImportCSV_MissingUrlColumn(t *testing.T) {
+	mockStore := new(MockStore)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ImportCSV(nil, 3, strings.NewReader("slug,expires_at\npromo,\n"))
+
+	assert.ErrorIs(t, err, ErrInvalidCSV)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestExportCSV_WritesEveryBatch(t *testing.T) {
+	mockStore := new(MockStore)
+	userID := types.UserId(3)
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	batches := [][]ShortModel{
+		{{ID: 1, OriginalUrl: "https://example.com/a", ShortUrl: "a", ClickCount: 2, CreatedAt: createdAt,
+			Tags: []TagModel{{Name: "news"}, {Name: "promo"}}}},
+		{{ID: 2, OriginalUrl: "https://example.com/b", ShortUrl: "b", Disabled: true, CreatedAt: createdAt}},
+	}
+	mockStore.On("Iterate", &userID, csvExportBatchSize).Return(batches, nil)
+
+	var out strings.Builder
+	service := NewShortService(mockStore, nil)
+	err := service.ExportCSV(nil, &userID, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "original_url,custom_short_url,expires_at,tags,id,max_clicks,click_count,enabled,created_at\n"+
+		"https://example.com/a,a,,news;promo,1,,2,true,2025-01-02T03:04:05Z\n"+
+		"https://example.com/b,b,,,2,,0,false,2025-01-02T03:04:05Z\n", out.String())
+}
+
+// QR Code Tests //
+func TestQRCode_CacheHit(t *testing.T) {
+	mockRedis := new(MockRedis)
+	req := QRCodeRequest{Format: "svg"}
+
+	mockRedis.On("Get", mock.Anything, qrCodeKey("https://sho.rt/abc", req.withDefaults())).Return("<svg/>", nil)
+
+	service := NewShortService(nil, mockRedis)
+	image, err := service.QRCode(nil, "https://sho.rt/abc", req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("<svg/>"), image)
+	mockRedis.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestQRCode_RendersAndCaches(t *testing.T) {
+	mockRedis := new(MockRedis)
+	req := QRCodeRequest{Format: "svg", Size: 128, Level: "h", Foreground: "#FF0000"}
+	key := qrCodeKey("https://sho.rt/abc", req.withDefaults())
+
+	mockRedis.On("Get", mock.Anything, key).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, key, mock.Anything, qrCacheTTL).Return(nil)
+
+	service := NewShortService(nil, mockRedis)
+	image, err := service.QRCode(nil, "https://sho.rt/abc", req)
+
+	assert.NoError(t, err)
+	assert.Contains(t, string(image), `fill="#ff0000"`)
+	assert.Contains(t, string(image), `width="128"`)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestQRCode_InvalidColour(t *testing.T) {
+	mockRedis := new(MockRedis)
+
+	service := NewShortService(nil, mockRedis)
+	_, err := service.QRCode(nil, "https://sho.rt/abc", QRCodeRequest{Background: "blue"})
+
+	assert.ErrorIs(t, err, ErrInvalidQRRequest)
+	mockRedis.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+// Custom Domain Tests //
+func TestResolve_UsesDomainOfHost(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	mockDomains := new(MockDomains)
+	slug := "x"
+	domainID := types.DomainId(4)
+	short := ShortModel{ID: 1, DomainID: domainID, OriginalUrl: "https://brand.com", ShortUrl: slug}
+
+	mockDomains.On("DomainIDForHost", "go.brand.com").Return(domainID, nil)
+	mockRedis.On("Get", mock.Anything, shortByShortUrlKey(domainID, slug)).Return("", redis.Nil)
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: &domainID}).Return([]ShortModel{short}, nil)
+	mockRedis.On("Set", mock.Anything, shortByShortUrlKey(domainID, slug), mock.Anything, mock.Anything).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis, WithDomainLookup(mockDomains))
+	result, err := service.Resolve(nil, "go.brand.com", slug)
+
+	assert.NoError(t, err)
+	assert.Equal(t, short, result)
+	mockStore.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestShortenURL_DomainNotUsable(t *testing.T) {
+	mockStore := new(MockStore)
+	mockDomains := new(MockDomains)
+
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", DomainID: 4}
+	mockDomains.On("CheckDomainUse", types.UserId(1), types.DomainId(4)).Return(errors.New("Domain is not verified"))
+
+	service := NewShortService(mockStore, nil, WithDomainLookup(mockDomains))
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrDomainUnavailable)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlugCheckedWithinDomain(t *testing.T) {
+	mockStore := new(MockStore)
+	mockDomains := new(MockDomains)
+	slug := "launch"
+	domainID := types.DomainId(4)
+
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug, DomainID: domainID}
+	expected := ShortModel{UserID: 1, OriginalUrl: req.Url, ShortUrl: slug, DomainID: domainID, RedirectStatus: DefaultRedirectStatus}
+
+	mockDomains.On("CheckDomainUse", types.UserId(1), domainID).Return(nil)
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: &domainID}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", expected).Return(expected, nil)
+	mockStore.On("GetRelease", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	service := NewShortService(mockStore, nil, WithDomainLookup(mockDomains))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domainID, result.DomainID)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortLink_CustomDomain(t *testing.T) {
+	mockDomains := new(MockDomains)
+	mockDomains.On("HostForDomain", types.DomainId(4)).Return("go.brand.com", nil)
+
+	service := NewShortService(nil, nil, WithDomainLookup(mockDomains))
+
+	assert.Equal(t, "https://go.brand.com/x", service.ShortLink(nil, ShortModel{DomainID: 4, ShortUrl: "x"}, "https://sho.rt/"))
+	assert.Equal(t, "https://sho.rt/y", service.ShortLink(nil, ShortModel{ShortUrl: "y"}, "https://sho.rt/"))
+}
+
+// Tags and Folders Tests //
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"news", "q3"}, normalizeTags([]string{" News", "q3", "", "NEWS "}))
+}
+
+func TestShortenURL_WithTagsAndFolder(t *testing.T) {
+	mockStore := new(MockStore)
+	folderID := types.FolderId(5)
+	tags := []TagModel{{ID: 1, UserID: 2, Name: "launch"}}
+
+	req := ShortenRequest{UserID: 2, Url: "https://example.com", Tags: []string{"Launch"}, FolderID: &folderID}
+	expected := ShortModel{UserID: 2, OriginalUrl: req.Url, ShortUrl: "code", RedirectStatus: DefaultRedirectStatus, FolderID: &folderID, Tags: tags}
+
+	mockStore.On("GetFolder", folderID).Return(FolderModel{ID: folderID, UserID: 2}, nil)
+	mockStore.On("EnsureTags", types.UserId(2), []string{"launch"}).Return(tags, nil)
+	mockStore.On("Create", expected).Return(expected, nil)
+
+	service := NewShortService(mockStore, nil, WithCodeGenerator(&sequenceGenerator{codes: []string{"code"}}))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"launch"}, result.TagNames())
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_FolderOfAnotherUser(t *testing.T) {
+	mockStore := new(MockStore)
+	folderID := types.FolderId(5)
+
+	mockStore.On("GetFolder", folderID).Return(FolderModel{ID: folderID, UserID: 9}, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, ShortenRequest{UserID: 2, Url: "https://example.com", FolderID: &folderID})
+
+	assert.ErrorIs(t, err, ErrFolderNotFound)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUpdateShort_ReplacesTagsAndLeavesFolder(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	folderID := types.FolderId(5)
+	short := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", FolderID: &folderID}
+	tags := []TagModel{{ID: 4, UserID: 2, Name: "archived"}}
+	none := types.FolderId(0)
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.MatchedBy(func(s ShortModel) bool { return s.FolderID == nil })).Return(ShortModel{ID: 3, UserID: 2, ShortUrl: "abc"}, nil)
+	mockStore.On("EnsureTags", types.UserId(2), []string{"archived"}).Return(tags, nil)
+	mockStore.On("SetTags", types.ShortId(3), tags).Return(nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{Tags: &[]string{"archived"}, FolderID: &none})
+
+	assert.NoError(t, err)
+	assert.Nil(t, updated.FolderID)
+	assert.Equal(t, tags, updated.Tags)
+	mockStore.AssertExpectations(t)
+}
+
+func TestDeleteFolder_NotOwned(t *testing.T) {
+	mockStore := new(MockStore)
+
+	mockStore.On("GetFolder", types.FolderId(5)).Return(FolderModel{ID: 5, UserID: 9}, nil)
+
+	service := NewShortService(mockStore, nil)
+	err := service.DeleteFolder(nil, 2, 5)
+
+	assert.ErrorIs(t, err, ErrFolderNotFound)
+	mockStore.AssertNotCalled(t, "DeleteFolder", mock.Anything)
+}
+
+func TestSearchOrder(t *testing.T) {
+	desc, asc, unknown := "-click_count", "created_at", "password_hash"
+
+	assert.Equal(t, "click_count DESC, id DESC", searchOrder(&desc))
+	assert.Equal(t, "created_at, id", searchOrder(&asc))
+	assert.Equal(t, "id", searchOrder(&unknown))
+	assert.Equal(t, "id", searchOrder(nil))
+}
+
+func TestResolve_NotActive(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	activateAt := time.Now().Add(time.Hour)
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{{ID: 1, ShortUrl: "launch", ActivateAt: &activateAt}}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.Resolve(nil, "", "launch")
+
+	var notActive *NotActiveError
+	assert.ErrorIs(t, err, ErrShortNotActive)
+	assert.ErrorAs(t, err, &notActive)
+	assert.Empty(t, notActive.ComingSoonUrl)
+}
+
+func TestResolve_NotActive_ComingSoonUrl(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+
+	activateAt := time.Now().Add(time.Hour)
+	mockRedis.On("Get", mock.Anything, mock.Anything).Return("", redis.Nil)
+	mockRedis.On("Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{{ID: 1, ShortUrl: "own", ActivateAt: &activateAt, ComingSoonUrl: "https://example.com/soon"}}, nil).Once()
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{{ID: 2, ShortUrl: "default", ActivateAt: &activateAt}}, nil).Once()
+
+	service := NewShortService(mockStore, mockRedis, WithComingSoonUrl("https://example.com/teaser"))
+
+	var notActive *NotActiveError
+	_, err := service.Resolve(nil, "", "own")
+	assert.ErrorAs(t, err, &notActive)
+	assert.Equal(t, "https://example.com/soon", notActive.ComingSoonUrl)
+
+	_, err = service.Resolve(nil, "", "default")
+	assert.ErrorAs(t, err, &notActive)
+	assert.Equal(t, "https://example.com/teaser", notActive.ComingSoonUrl)
+}
+
+func TestShortenURL_InvalidSchedule(t *testing.T) {
+	mockStore := new(MockStore)
+
+	now := time.Now()
+	activateAt, expiresAt := now.Add(2*time.Hour), now.Add(time.Hour)
+	service := NewShortService(mockStore, nil)
+
+	_, err := service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: "https://example.com", ActivateAt: &activateAt, ExpiresAt: &expiresAt})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: "https://example.com", Destinations: []DestinationRequest{
+		{Url: "https://example.com/sale", StartsAt: &activateAt, EndsAt: &expiresAt},
+	}})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_ScheduledIsNotDeduplicated(t *testing.T) {
+	mockStore := new(MockStore)
+
+	activateAt := time.Now().Add(time.Hour)
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", ActivateAt: &activateAt, Destinations: []DestinationRequest{
+		{Url: "https://example.com/launch", StartsAt: &activateAt},
+	}}
+	mockStore.On("Create", mock.MatchedBy(func(s ShortModel) bool {
+		return s.ActivateAt == &activateAt && len(s.Destinations) == 1 && s.Destinations[0].Url == "https://example.com/launch"
+	})).Return(ShortModel{ID: 1, ShortUrl: "abc"}, nil)
+
+	service := NewShortService(mockStore, nil, WithCodeGenerator(&sequenceGenerator{codes: []string{"abc"}}))
+	_, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+	mockStore.AssertExpectations(t)
+}
+
+func TestUpdateShort_ReplacesDestinations(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	startsAt := time.Now().Add(time.Hour)
+	short := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc"}
+	destinations := []DestinationModel{{Url: "https://example.com/next", StartsAt: &startsAt}}
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.Anything).Return(short, nil)
+	mockStore.On("SetDestinations", types.ShortId(3), destinations).Return(nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{Destinations: &[]DestinationRequest{
+		{Url: "https://example.com/next", StartsAt: &startsAt},
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, destinations, updated.Destinations)
+	mockStore.AssertExpectations(t)
+}
+
+func TestTarget_RulesBeforeSchedule(t *testing.T) {
+	now := time.Now()
+	short := ShortModel{
+		OriginalUrl:  "https://example.com",
+		Destinations: []DestinationModel{{Url: "https://example.com/sale"}},
+		Rules: []RedirectRuleModel{
+			{OS: useragent.OSIOS, Url: "https://apps.apple.com/app/id1"},
+			{OS: useragent.OSAndroid, Url: "https://play.google.com/store/apps/details?id=app"},
+			{Device: useragent.DeviceDesktop, Language: "de", Url: "https://example.de"},
+		},
+	}
+
+	iphone := useragent.Parse("Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1")
+	pixel := useragent.Parse("Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Mobile Safari/537.36")
+	windows := useragent.Parse("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36")
+
+	target := func(visitor Visitor) string {
+		url, _ := short.Target(visitor, now)
+		return url
+	}
+
+	assert.Equal(t, "https://apps.apple.com/app/id1", target(Visitor{OS: iphone.OS, Device: iphone.Device}))
+	assert.Equal(t, "https://play.google.com/store/apps/details?id=app", target(Visitor{OS: pixel.OS, Device: pixel.Device}))
+	assert.Equal(t, "https://example.de", target(Visitor{
+		OS: windows.OS, Device: windows.Device, Language: useragent.PreferredLanguage("en;q=0.5, de-AT, fr;q=0.8"),
+	}))
+	assert.Equal(t, "https://example.com/sale", target(Visitor{OS: windows.OS, Device: windows.Device, Language: "en"}))
+}
+
+func TestShortenURL_RuleWithoutCondition(t *testing.T) {
+	mockStore := new(MockStore)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: "https://example.com", Rules: []RedirectRuleRequest{
+		{Url: "https://example.com/everyone"},
+	}})
+
+	assert.ErrorIs(t, err, ErrInvalidRedirectRule)
+	assert.Equal(t, "invalid_rule", ErrorCode(err))
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUpdateShort_ReplacesRules(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	short := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc"}
+	rules := []RedirectRuleModel{{OS: useragent.OSIOS, Language: "pt-br", Url: "https://apps.apple.com/br/app/id1"}}
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.Anything).Return(short, nil)
+	mockStore.On("SetRules", types.ShortId(3), rules).Return(nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{Rules: &[]RedirectRuleRequest{
+		{OS: useragent.OSIOS, Language: "pt-BR", Url: "https://apps.apple.com/br/app/id1"},
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, rules, updated.Rules)
+	mockStore.AssertExpectations(t)
+}
+
+func TestTarget_CountryRule(t *testing.T) {
+	locator := new(MockLocator)
+	locator.On("Country", "81.2.69.142").Return("GB", nil)
+	locator.On("Country", "10.0.0.1").Return("", nil)
+
+	service := NewShortService(new(MockStore), nil, WithGeoLocator(locator))
+	short := ShortModel{
+		OriginalUrl: "https://example.com",
+		Rules:       []RedirectRuleModel{{Country: "GB", Url: "https://example.co.uk"}},
+	}
+
+	assert.True(t, short.needsCountry())
+	uk, _ := short.Target(Visitor{Country: service.VisitorCountry("81.2.69.142")}, time.Now())
+	assert.Equal(t, "https://example.co.uk", uk)
+	private, _ := short.Target(Visitor{Country: service.VisitorCountry("10.0.0.1")}, time.Now())
+	assert.Equal(t, "https://example.com", private)
+}
+
+func TestVisitorCountry_WithoutLocator(t *testing.T) {
+	service := NewShortService(new(MockStore), nil)
+
+	assert.Empty(t, service.VisitorCountry("81.2.69.142"))
+}
+
+func TestPickVariant_ByWeight(t *testing.T) {
+	short := ShortModel{Variants: []VariantModel{
+		{ID: 1, Name: "a", Url: "https://example.com/a", Weight: 3},
+		{ID: 2, Name: "b", Url: "https://example.com/b", Weight: 1},
+	}}
+
+	assert.Equal(t, 4, short.variantWeight())
+	assert.Equal(t, types.VariantId(1), short.pickVariant(0))
+	assert.Equal(t, types.VariantId(1), short.pickVariant(2))
+	assert.Equal(t, types.VariantId(2), short.pickVariant(3))
+}
+
+func TestPinVariant_OnlyWhenVariantDecides(t *testing.T) {
+	short := ShortModel{
+		ID:          7,
+		OriginalUrl: "https://example.com",
+		Rules:       []RedirectRuleModel{{OS: useragent.OSIOS, Url: "https://apps.apple.com/app/id1"}},
+		Variants: []VariantModel{
+			{ID: 1, Name: "a", Url: "https://example.com/a", Weight: 1},
+			{ID: 2, Name: "b", Url: "https://example.com/b", Weight: 1},
+		},
+	}
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		visitor := Visitor{OS: c.Query("os"), Variant: assignVariant(c, short)}
+		destination, variantID := short.Target(visitor, time.Now())
+		pinVariant(c, short.ID, variantID)
+		return c.SendString(destination)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/?os="+useragent.OSIOS, nil))
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Cookies())
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	if assert.Len(t, resp.Cookies(), 1) {
+		assert.Equal(t, variantCookieName(7), resp.Cookies()[0].Name)
+	}
+}
+
+func TestTarget_Variant(t *testing.T) {
+	now := time.Now()
+	short := ShortModel{
+		OriginalUrl: "https://example.com",
+		Rules:       []RedirectRuleModel{{OS: useragent.OSIOS, Url: "https://apps.apple.com/app/id1"}},
+		Variants: []VariantModel{
+			{ID: 1, Name: "a", Url: "https://example.com/a", Weight: 1},
+			{ID: 2, Name: "b", Url: "https://example.com/b", Weight: 1},
+		},
+	}
+
+	url, variant := short.Target(Visitor{Variant: 2}, now)
+	assert.Equal(t, "https://example.com/b", url)
+	assert.Equal(t, types.VariantId(2), variant)
+
+	url, variant = short.Target(Visitor{OS: useragent.OSIOS, Variant: 2}, now)
+	assert.Equal(t, "https://apps.apple.com/app/id1", url)
+	assert.Zero(t, variant)
+
+	url, variant = short.Target(Visitor{Variant: 9}, now)
+	assert.Equal(t, "https://example.com", url)
+	assert.Zero(t, variant)
+}
+
+func TestShortenURL_SingleVariant(t *testing.T) {
+	mockStore := new(MockStore)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: "https://example.com", Variants: []VariantRequest{
+		{Url: "https://example.com/a"},
+	}})
+
+	assert.ErrorIs(t, err, ErrInvalidVariants)
+	assert.Equal(t, "invalid_variants", ErrorCode(err))
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestUpdateShort_ReplacesVariants(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	short := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc"}
+	variants := []VariantModel{
+		{Name: "a", Url: "https://example.com/a", Weight: 1},
+		{Name: "control", Url: "https://example.com/b", Weight: 4},
+	}
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.Anything).Return(short, nil)
+	mockStore.On("SetVariants", types.ShortId(3), variants).Return(nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{Variants: &[]VariantRequest{
+		{Url: "https://example.com/a"},
+		{Name: "control", Url: "https://example.com/b", Weight: 4},
+	}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, variants, updated.Variants)
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_RejectsUnsafeURLs(t *testing.T) {
+	mockStore := new(MockStore)
+
+	service := NewShortService(mockStore, nil)
+	for _, url := range []string{
+		"javascript:alert(1)",
+		"file:///etc/passwd",
+		"http://127.0.0.1:8080/admin",
+		"http://192.168.1.1",
+		"http://[::1]/",
+		"http://localhost:6379",
+		"http://169.254.169.254/latest/meta-data",
+	} {
+		_, err := service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: url})
+
+		assert.ErrorIs(t, err, ErrUnsafeURL, url)
+		assert.Equal(t, "unsafe_url", ErrorCode(err))
+	}
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_RejectsBlocklistedDestination(t *testing.T) {
+	mockStore := new(MockStore)
+	policy := DefaultURLPolicy()
+	policy.Blocklist = wordlist.New("evil.example")
+
+	service := NewShortService(mockStore, nil, WithURLPolicy(policy))
+	_, err := service.ShortenURL(nil, ShortenRequest{UserID: 1, Url: "https://example.com", Variants: []VariantRequest{
+		{Url: "https://example.com/a"},
+		{Url: "https://login.evil.example/a"},
+	}})
+
+	assert.ErrorIs(t, err, ErrUnsafeURL)
+	assert.Contains(t, err.Error(), "evil.example")
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestURLPolicy_Check(t *testing.T) {
+	scanner := new(MockScanner)
+	scanner.On("Scan", "https://phish.example/login").Return(ScanVerdict{Unsafe: true, Reason: "phishing"}, nil)
+	scanner.On("Scan", "https://example.com").Return(ScanVerdict{}, nil)
+	scanner.On("Scan", "https://example.org").Return(ScanVerdict{}, errors.New("scanner unavailable"))
+
+	policy := &URLPolicy{
+		AllowedSchemes: DefaultAllowedSchemes,
+		Resolver:       stubHostResolver{"intranet.example": {{IP: net.ParseIP("10.1.2.3")}}},
+		Scanner:        scanner,
+	}
+
+	err := policy.Check(context.Background(), "https://phish.example/login")
+	assert.ErrorIs(t, err, ErrUnsafeURL)
+	assert.Contains(t, err.Error(), "phishing")
+	assert.ErrorIs(t, policy.Check(context.Background(), "https://intranet.example"), ErrUnsafeURL)
+	assert.NoError(t, policy.Check(context.Background(), "https://example.com"))
+	assert.NoError(t, policy.Check(context.Background(), "https://example.org"))
+
+	policy.AllowPrivate = true
+	scanner.On("Scan", "http://192.168.1.1").Return(ScanVerdict{}, nil)
+	assert.NoError(t, policy.Check(context.Background(), "http://192.168.1.1"))
+}
+
+func TestEnforceBlocklist(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	policy := DefaultURLPolicy()
+	policy.Blocklist = wordlist.New("evil.example")
+
+	batch := []ShortModel{
+		{ID: 1, ShortUrl: "a", OriginalUrl: "https://www.evil.example/"},
+		{ID: 2, ShortUrl: "b", OriginalUrl: "https://example.com", BlockedReason: "blocklisted domain gone.example"},
+		{ID: 3, ShortUrl: "c", OriginalUrl: "https://example.com"},
+		{ID: 4, ShortUrl: "d", OriginalUrl: "https://example.com", Rules: []RedirectRuleModel{{OS: useragent.OSIOS, Url: "https://evil.example/app"}}},
+	}
+	mockStore.On("Iterate", (*types.UserId)(nil), blocklistBatchSize).Return([][]ShortModel{batch}, nil)
+	mockStore.On("SetBlockedReason", types.ShortId(1), "blocklisted domain evil.example").Return(nil).Once()
+	mockStore.On("SetBlockedReason", types.ShortId(2), "").Return(nil).Once()
+	mockStore.On("SetBlockedReason", types.ShortId(4), "blocklisted domain evil.example").Return(nil).Once()
+	mockRedis.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis, WithURLPolicy(policy))
+	changed, err := service.EnforceBlocklist(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, changed)
+	mockStore.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "SetBlockedReason", types.ShortId(3), mock.Anything)
+}
+
+func TestResolve_Blocked(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	short := ShortModel{ID: 1, ShortUrl: "abc", OriginalUrl: "https://evil.example", BlockedReason: "blocklisted domain evil.example"}
+	cached, _ := json.Marshal(short)
+	mockRedis.On("Get", mock.Anything, shortByShortUrlKey(0, "abc")).Return(string(cached), nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.Resolve(context.Background(), "", "abc")
+
+	assert.ErrorIs(t, err, ErrShortBlocked)
+}
+
+func TestCreatorName(t *testing.T) {
+	service := NewShortService(new(MockStore), nil, WithCreatorLookup(stubCreators{2: "alice"}))
+
+	assert.Equal(t, "alice", service.CreatorName(context.Background(), ShortModel{UserID: 2}))
+	assert.Empty(t, service.CreatorName(context.Background(), ShortModel{UserID: 3}))
+	assert.Empty(t, NewShortService(new(MockStore), nil).CreatorName(context.Background(), ShortModel{UserID: 2}))
+}
+
+func TestUpdateShort_AlwaysPreview(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	short := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", OriginalUrl: "https://example.com"}
+	preview := true
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.MatchedBy(func(s ShortModel) bool { return s.AlwaysPreview })).
+		Return(ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", OriginalUrl: "https://example.com", AlwaysPreview: true}, nil)
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{AlwaysPreview: &preview})
+
+	assert.NoError(t, err)
+	assert.True(t, updated.AlwaysPreview)
+	assert.True(t, updated.hasOptions())
+	mockStore.AssertExpectations(t)
+}
+
+func TestShortenURL_PublishesMetadataJob(t *testing.T) {
+	mockStore := new(MockStore)
+	mq := new(MockMessaging)
+
+	req := ShortenRequest{UserID: 2, Url: "https://example.com"}
+	created := ShortModel{ID: 9, UserID: 2, OriginalUrl: req.Url, ShortUrl: "abc"}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(created, nil)
+	mq.On("Publish", "metadata", `{"short_id":9,"url":"https://example.com"}`).Return(nil).Once()
+
+	service := NewShortService(mockStore, nil, WithMetadataJobs(mq, "metadata"))
+	_, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	mq.AssertExpectations(t)
+}
+
+func TestRefreshMetadata(t *testing.T) {
+	mockStore := new(MockStore)
+	short := ShortModel{ID: 9, OriginalUrl: "https://example.com"}
+	fetcher := stubFetcher{"https://example.com": {
+		Title:      "Example Domain",
+		SiteName:   "Example",
+		FaviconUrl: "https://example.com/favicon.ico",
+	}}
+
+	mockStore.On("GetById", types.ShortId(9)).Return(short, nil)
+	mockStore.On("SetMetadata", types.ShortId(9), mock.MatchedBy(func(meta LinkMetadata) bool {
+		return meta.Title == "Example Domain" && meta.SiteName == "Example" &&
+			meta.FaviconUrl == "https://example.com/favicon.ico" && meta.FetchedAt != nil
+	})).Return(nil).Once()
+
+	service := NewShortService(mockStore, nil, WithMetadataFetcher(fetcher))
+	err := service.RefreshMetadata(context.Background(), MetadataJob{ShortID: 9, Url: "https://example.com"})
+
+	assert.NoError(t, err)
+	mockStore.AssertExpectations(t)
+}
+
+func TestRefreshMetadata_FetchFailureMarksFetched(t *testing.T) {
+	mockStore := new(MockStore)
+	short := ShortModel{ID: 9, OriginalUrl: "https://example.com/gone"}
+
+	mockStore.On("GetById", types.ShortId(9)).Return(short, nil)
+	mockStore.On("SetMetadata", types.ShortId(9), mock.MatchedBy(func(meta LinkMetadata) bool {
+		return meta.Title == "" && meta.FetchedAt != nil
+	})).Return(nil).Once()
+
+	service := NewShortService(mockStore, nil, WithMetadataFetcher(stubFetcher{}))
+	err := service.RefreshMetadata(context.Background(), MetadataJob{ShortID: 9, Url: "https://example.com/gone"})
+
+	assert.ErrorIs(t, err, ErrMetadataFetchFail)
+	mockStore.AssertExpectations(t)
+}
+
+func TestRefreshMetadata_SkipsStaleJob(t *testing.T) {
+	mockStore := new(MockStore)
+	short := ShortModel{ID: 9, OriginalUrl: "https://example.com/new"}
+
+	mockStore.On("GetById", types.ShortId(9)).Return(short, nil)
+
+	service := NewShortService(mockStore, nil, WithMetadataFetcher(stubFetcher{}))
+	err := service.RefreshMetadata(context.Background(), MetadataJob{ShortID: 9, Url: "https://example.com/old"})
+
+	assert.NoError(t, err)
+	mockStore.AssertNotCalled(t, "SetMetadata", mock.Anything, mock.Anything)
+}
+
+func TestCheckHealth(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	checkedAt := time.Now().Add(-time.Hour)
+
+	batch := []ShortModel{
+		{ID: 1, ShortUrl: "a", OriginalUrl: "https://example.com/ok"},
+		{ID: 2, ShortUrl: "b", OriginalUrl: "https://example.com/gone", FallbackUrl: "https://example.com/",
+			Health: LinkHealth{Status: 404, Failures: 2, CheckedAt: &checkedAt}},
+		{ID: 3, ShortUrl: "c", OriginalUrl: "https://example.com/back",
+			Health: LinkHealth{Status: 500, Failures: 5, Broken: true, CheckedAt: &checkedAt}},
+		{ID: 4, ShortUrl: "d", OriginalUrl: "https://example.com/gone", Disabled: true},
+	}
+	checker := stubChecker{
+		"https://example.com/ok":   {Status: 200, Latency: 30 * time.Millisecond},
+		"https://example.com/gone": {Status: 404},
+		"https://example.com/back": {Status: 301},
+	}
+
+	mockStore.On("Iterate", (*types.UserId)(nil), blocklistBatchSize).Return([][]ShortModel{batch}, nil)
+	mockStore.On("SetHealth", types.ShortId(1), mock.MatchedBy(func(h LinkHealth) bool {
+		return h.Status == 200 && h.LatencyMs == 30 && h.Failures == 0 && !h.Broken && h.CheckedAt != nil
+	})).Return(nil).Once()
+	mockStore.On("SetHealth", types.ShortId(2), mock.MatchedBy(func(h LinkHealth) bool {
+		return h.Status == 404 && h.Failures == 3 && h.Broken
+	})).Return(nil).Once()
+	mockStore.On("SetHealth", types.ShortId(3), mock.MatchedBy(func(h LinkHealth) bool {
+		return h.Status == 301 && h.Failures == 0 && !h.Broken
+	})).Return(nil).Once()
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "b")).Return(nil).Once()
+
+	service := NewShortService(mockStore, mockRedis, WithLinkChecker(checker, HealthConfig{HostInterval: time.Millisecond}))
+	report, err := service.CheckHealth(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, HealthReport{Checked: 3, Broken: 1}, report)
+	mockStore.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "SetHealth", types.ShortId(4), mock.Anything)
+}
+
+func TestTarget_FallbackWhileBroken(t *testing.T) {
+	short := ShortModel{
+		OriginalUrl: "https://example.com/gone",
+		FallbackUrl: "https://example.com/",
+		Health:      LinkHealth{Broken: true},
+	}
+
+	destination, _ := short.Target(Visitor{}, time.Now())
+	assert.Equal(t, "https://example.com/", destination)
+
+	short.Health.Broken = false
+	destination, _ = short.Target(Visitor{}, time.Now())
+	assert.Equal(t, "https://example.com/gone", destination)
+}
+
+func TestUpdateShort_NewDestinationResetsHealth(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	checkedAt := time.Now()
+	short := ShortModel{ID: 3, ShortUrl: "abc", OriginalUrl: "https://example.com/gone",
+		Health: LinkHealth{Status: 404, Failures: 3, Broken: true, CheckedAt: &checkedAt}}
+	newUrl := "https://example.com/new"
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.Anything).Return(ShortModel{ID: 3, ShortUrl: "abc", OriginalUrl: newUrl, Health: short.Health}, nil)
+	mockStore.On("SetHealth", types.ShortId(3), LinkHealth{}).Return(nil).Once()
+	mockRedis.On("Delete", mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{}, nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{OriginalUrl: &newUrl})
+
+	assert.NoError(t, err)
+	assert.Nil(t, NewShortResponse(updated).Health)
+	mockStore.AssertExpectations(t)
+}
+
+func TestUpdateShort_UnchangedRecordsNoHistory(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	short := ShortModel{ID: 3, ShortUrl: "abc", OriginalUrl: "https://example.com"}
+	sameUrl := short.OriginalUrl
+
+	mockStore.On("GetById", types.ShortId(3)).Return(short, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", short).Return(short, nil)
+	mockRedis.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	_, err := service.UpdateShort(nil, 2, 3, UpdateShortRequest{OriginalUrl: &sameUrl})
+
+	assert.NoError(t, err)
+	mockStore.AssertNotCalled(t, "AddHistory", mock.Anything)
+}
+
+func TestRollback(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	folder := types.FolderId(4)
+	current := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", OriginalUrl: "https://example.com/new", ForwardQuery: true,
+		Tags: []TagModel{{ID: 1, Name: "new"}}}
+	version := ShortHistoryModel{ShortID: 3, Version: 2, New: ShortSnapshot{
+		OriginalUrl:    "https://example.com/old",
+		ShortUrl:       "abc",
+		Enabled:        true,
+		RedirectStatus: DefaultRedirectStatus,
+		FolderID:       &folder,
+		Tags:           []string{"old"},
+	}}
+	oldTags := []TagModel{{ID: 2, Name: "old"}}
+	restored := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", OriginalUrl: "https://example.com/old",
+		RedirectStatus: DefaultRedirectStatus, FolderID: &folder}
+
+	mockStore.On("GetById", types.ShortId(3)).Return(current, nil)
+	mockStore.On("GetHistoryVersion", types.ShortId(3), 2).Return(version, nil)
+	mockStore.On("GetFolder", folder).Return(FolderModel{ID: folder, UserID: 2}, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.MatchedBy(func(s ShortModel) bool {
+		return s.OriginalUrl == "https://example.com/old" && !s.ForwardQuery && *s.FolderID == folder
+	})).Return(restored, nil)
+	mockStore.On("EnsureTags", types.UserId(2), []string{"old"}).Return(oldTags, nil)
+	mockStore.On("SetTags", types.ShortId(3), oldTags).Return(nil)
+	mockStore.On("SetDestinations", types.ShortId(3), []DestinationModel(nil)).Return(nil)
+	mockStore.On("SetRules", types.ShortId(3), []RedirectRuleModel(nil)).Return(nil)
+	mockStore.On("SetVariants", types.ShortId(3), []VariantModel(nil)).Return(nil)
+	mockStore.On("AddHistory", mock.MatchedBy(func(entry ShortHistoryModel) bool {
+		return entry.EditorID == 5 && entry.RolledBackTo != nil && *entry.RolledBackTo == 2 &&
+			entry.Old.OriginalUrl == "https://example.com/new" && entry.New.OriginalUrl == "https://example.com/old"
+	})).Return(ShortHistoryModel{Version: 3}, nil).Once()
+	mockRedis.On("Delete", mock.Anything, shortByShortUrlKey(0, "abc")).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.Rollback(context.Background(), 5, 3, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/old", updated.OriginalUrl)
+	assert.Equal(t, []string{"old"}, updated.TagNames())
+	mockStore.AssertExpectations(t)
+	mockRedis.AssertExpectations(t)
+}
+
+func TestRollback_BeforeFirstChange(t *testing.T) {
+	mockStore := new(MockStore)
+	mockRedis := new(MockRedis)
+	current := ShortModel{ID: 3, ShortUrl: "abc", OriginalUrl: "https://example.com/new"}
+	first := ShortHistoryModel{ShortID: 3, Version: 1,
+		Old: ShortSnapshot{OriginalUrl: "https://example.com/first", ShortUrl: "abc", Enabled: true, RedirectStatus: DefaultRedirectStatus},
+		New: ShortSnapshot{OriginalUrl: "https://example.com/new", ShortUrl: "abc", Enabled: true, RedirectStatus: DefaultRedirectStatus},
+	}
+
+	mockStore.On("GetById", types.ShortId(3)).Return(current, nil)
+	mockStore.On("GetHistoryVersion", types.ShortId(3), 1).Return(first, nil)
+	mockStore.On("Transaction").Return()
+	mockStore.On("Update", mock.MatchedBy(func(s ShortModel) bool {
+		return s.OriginalUrl == "https://example.com/first"
+	})).Return(ShortModel{ID: 3, ShortUrl: "abc", OriginalUrl: "https://example.com/first"}, nil)
+	mockStore.On("EnsureTags", mock.Anything, mock.Anything).Return([]TagModel{}, nil)
+	mockStore.On("SetTags", mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("SetDestinations", mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("SetRules", mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("SetVariants", mock.Anything, mock.Anything).Return(nil)
+	mockStore.On("AddHistory", mock.Anything).Return(ShortHistoryModel{Version: 2}, nil)
+	mockRedis.On("Delete", mock.Anything, mock.Anything).Return(nil)
+
+	service := NewShortService(mockStore, mockRedis)
+	updated, err := service.Rollback(context.Background(), 5, 3, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/first", updated.OriginalUrl)
+}
+
+func TestRollback_UnknownVersion(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("GetById", types.ShortId(3)).Return(ShortModel{ID: 3, ShortUrl: "abc"}, nil)
+	mockStore.On("GetHistoryVersion", types.ShortId(3), 7).Return(ShortHistoryModel{}, ErrVersionNotFound)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.Rollback(context.Background(), 5, 3, 7)
+
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	mockStore.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestRestoreURLForUser(t *testing.T) {
+	mockStore := new(MockStore)
+	trashed := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc", DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+	restored := ShortModel{ID: 3, UserID: 2, ShortUrl: "abc"}
+
+	mockStore.On("GetDeleted", types.ShortId(3)).Return(trashed, nil)
+	mockStore.On("Restore", types.ShortId(3)).Return(nil).Once()
+	mockStore.On("GetById", types.ShortId(3)).Return(restored, nil)
+
+	service := NewShortService(mockStore, nil)
+	short, err := service.RestoreURLForUser(context.Background(), 2, 3)
+
+	assert.NoError(t, err)
+	assert.Nil(t, NewShortResponse(short).DeletedAt)
+	mockStore.AssertExpectations(t)
+
+	_, err = service.RestoreURLForUser(context.Background(), 5, 3)
+	assert.ErrorIs(t, err, ErrShortNotOwned)
+	mockStore.AssertNumberOfCalls(t, "Restore", 1)
+}
+
+func TestRestoreURL_NotInTrash(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("Restore", types.ShortId(3)).Return(fmt.Errorf("%w: 3 is not in the trash", ErrShortNotFound))
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.RestoreURL(context.Background(), 3)
+
+	assert.ErrorIs(t, err, ErrShortNotFound)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	mockStore := new(MockStore)
+	mockStore.On("Purge", mock.MatchedBy(func(before time.Time) bool {
+		cutoff := time.Now().Add(-DefaultTrashRetention)
+		return before.Sub(cutoff).Abs() < time.Minute
+	})).Return(4, nil)
+	mockStore.On("DeleteReleases", mock.Anything).Return(nil)
+
+	service := NewShortService(mockStore, nil)
+	purged, err := service.PurgeTrash(context.Background(), DefaultTrashRetention)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, purged)
+}
+
+func TestNewShortResponse_DeletedAt(t *testing.T) {
+	deletedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	response := NewShortResponse(ShortModel{ID: 3, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}})
+
+	assert.Equal(t, &deletedAt, response.DeletedAt)
+}
+
+func TestShortenURL_CustomSlug_TooShortForUser(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "abc"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	service := NewShortService(mockStore, nil, WithSlugPolicy(&SlugPolicy{
+		MinLength:      DefaultSlugMinLength,
+		AdminMinLength: DefaultSlugAdminMinLength,
+		Roles:          stubRoles{1: "user"},
+	}))
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortUrlTooShort)
+	assert.Equal(t, "short_url_too_short", ErrorCode(err))
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlug_ShortForAdmin(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "go"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+	created := ShortModel{ID: 1, UserID: 1, OriginalUrl: "https://example.com", ShortUrl: slug}
+
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(created, nil)
+
+	service := NewShortService(mockStore, nil, WithSlugPolicy(&SlugPolicy{
+		MinLength:      DefaultSlugMinLength,
+		AdminMinLength: DefaultSlugAdminMinLength,
+		Roles:          stubRoles{1: "admin"},
+	}))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, result)
+}
+
+func TestSlugPolicy_CheckWords(t *testing.T) {
+	policy := &SlugPolicy{
+		Reserved:   wordlist.New("pricing"),
+		Profanity:  wordlist.New("darn"),
+		Trademarks: wordlist.New("acme"),
+	}
+
+	tests := []struct {
+		slug string
+		want error
+	}{
+		{"spring-sale", nil},
+		{"Pricing", ErrReservedShortUrl},
+		{"swagger", ErrReservedShortUrl},
+		{"darnit", ErrProfaneShortUrl},
+		{"da-rn", ErrProfaneShortUrl},
+		{"acme", ErrTrademarkShortUrl},
+		{"ACME_deals", ErrTrademarkShortUrl},
+		{"ac-me", ErrTrademarkShortUrl},
+		{"acmecorp", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.slug, func(t *testing.T) {
+			err := policy.checkWords(tt.slug)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortenURL_GeneratedCodeSkipsBlockedWords(t *testing.T) {
+	mockStore := new(MockStore)
+
+	req := ShortenRequest{UserID: 1, Url: "https://example.com"}
+	created := ShortModel{ID: 1, UserID: 1, OriginalUrl: "https://example.com", ShortUrl: "clean"}
+
+	mockStore.On("Search", mock.Anything).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.MatchedBy(func(short ShortModel) bool { return short.ShortUrl == "clean" })).Return(created, nil)
+
+	service := NewShortService(mockStore, nil,
+		WithCodeGenerator(&sequenceGenerator{codes: []string{"xdarnx", "clean"}}),
+		WithSlugPolicy(&SlugPolicy{Profanity: wordlist.New("darn")}),
+	)
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, result)
+	mockStore.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestShortenURL_CustomSlug_CoolingDown(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "launch"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+
+	mockStore.On("GetRelease", types.DomainId(0), slug, mock.MatchedBy(func(since time.Time) bool {
+		cutoff := time.Now().Add(-DefaultSlugCooldown)
+		return since.Sub(cutoff).Abs() < time.Minute
+	})).Return(&ReleasedSlugModel{ShortUrl: slug, UserID: 2, ReleasedAt: time.Now().Add(-time.Hour)}, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrShortUrlCoolingDown)
+	assert.Equal(t, "short_url_cooling_down", ErrorCode(err))
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestShortenURL_CustomSlug_ReclaimedByPreviousOwner(t *testing.T) {
+	mockStore := new(MockStore)
+
+	slug := "launch"
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CustomShortUrl: &slug}
+	created := ShortModel{ID: 5, UserID: 1, OriginalUrl: "https://example.com", ShortUrl: slug}
+
+	mockStore.On("GetRelease", types.DomainId(0), slug, mock.Anything).
+		Return(&ReleasedSlugModel{ShortUrl: slug, UserID: 1, ReleasedAt: time.Now().Add(-time.Hour)}, nil)
+	mockStore.On("Search", SearchRequest{ShortUrl: &slug, DomainID: new(types.DomainId)}).Return([]ShortModel{}, ErrShortNotFound)
+	mockStore.On("Create", mock.Anything).Return(created, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, result)
+}
+
+func TestShortenURL_CampaignMergesUTM(t *testing.T) {
+	mockStore := new(MockStore)
+
+	campaignID := types.CampaignId(4)
+	req := ShortenRequest{UserID: 1, Url: "https://example.com/page?utm_source=twitter&ref=1", CampaignID: &campaignID}
+	merged := "https://example.com/page?ref=1&utm_campaign=spring&utm_medium=email&utm_source=twitter"
+	created := ShortModel{ID: 1, UserID: 1, OriginalUrl: merged, ShortUrl: "code", CampaignID: &campaignID}
+
+	mockStore.On("GetCampaign", campaignID).Return(CampaignModel{
+		ID: campaignID, UserID: 1, Name: "Spring", UtmSource: "newsletter", UtmMedium: "email", UtmCampaign: "spring",
+	}, nil)
+	mockStore.On("Create", ShortModel{
+		UserID: 1, OriginalUrl: merged, ShortUrl: "code", RedirectStatus: DefaultRedirectStatus, CampaignID: &campaignID,
+	}).Return(created, nil)
+
+	service := NewShortService(mockStore, nil, WithCodeGenerator(&sequenceGenerator{codes: []string{"code"}}))
+	result, err := service.ShortenURL(nil, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, created, result)
+	mockStore.AssertNotCalled(t, "Search", mock.Anything)
+}
+
+func TestShortenURL_OtherUsersCampaign(t *testing.T) {
+	mockStore := new(MockStore)
+
+	campaignID := types.CampaignId(4)
+	req := ShortenRequest{UserID: 1, Url: "https://example.com", CampaignID: &campaignID}
+
+	mockStore.On("GetCampaign", campaignID).Return(CampaignModel{ID: campaignID, UserID: 2, UtmSource: "newsletter"}, nil)
+
+	service := NewShortService(mockStore, nil)
+	_, err := service.ShortenURL(nil, req)
+
+	assert.ErrorIs(t, err, ErrCampaignNotFound)
+	assert.Equal(t, "campaign_not_found", ErrorCode(err))
+	mockStore.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestMergeUTM_KeepsUrlWithoutNewParameters(t *testing.T) {
+	raw := "https://example.com/?utm_source=a&utm_medium=b"
+
+	assert.Equal(t, raw, mergeUTM(raw, CampaignModel{UtmSource: "x", UtmMedium: "y"}))
+	assert.Equal(t, raw, mergeUTM(raw, CampaignModel{}))
+}
+
+func TestCreateCampaign_DefaultsUtmCampaignToName(t *testing.T) {
+	mockStore := new(MockStore)
+
+	expected := CampaignModel{UserID: 1, Name: "Spring Sale", UtmSource: "newsletter", UtmCampaign: "Spring Sale"}
+	mockStore.On("CreateCampaign", expected).Return(expected, nil)
+
+	service := NewShortService(mockStore, nil)
+	result, err := service.CreateCampaign(nil, 1, CampaignCreateRequest{Name: " Spring Sale ", UtmSource: "newsletter"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+	mockStore.AssertExpectations(t)
+}
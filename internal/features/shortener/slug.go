@@ -0,0 +1,187 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/Kalmera74/Shorty/pkg/wordlist"
+)
+
+// slugPattern is the set of characters a short code may contain. The redirect
+// route registered in RegisterRoutes is constrained by the same pattern.
+const slugPattern = `[a-zA-Z0-9_-]+`
+
+var slugRegexp = regexp.MustCompile(`^` + slugPattern + `$`)
+
+const (
+	// DefaultSlugMinLength is the shortest custom short URL a regular user
+	// may take.
+	DefaultSlugMinLength = 4
+	// DefaultSlugAdminMinLength is the shortest custom short URL an admin
+	// may take.
+	DefaultSlugAdminMinLength = 1
+	// DefaultSlugCooldown is how long a released short URL is kept from
+	// other users.
+	DefaultSlugCooldown = 90 * 24 * time.Hour
+)
+
+// reservedSlugs collide with paths served by the application itself. They
+// are reserved whatever the policy's Reserved list says.
+var reservedSlugs = map[string]struct{}{
+	"admin":    {},
+	"api":      {},
+	"assets":   {},
+	"docs":     {},
+	"health":   {},
+	"login":    {},
+	"logout":   {},
+	"me":       {},
+	"metrics":  {},
+	"register": {},
+	"static":   {},
+	"swagger":  {},
+}
+
+// slugSeparators split a short URL into the words trademarks are looked up
+// by, and slugJoiner drops them to find words spelled across them.
+var (
+	slugSeparators = strings.NewReplacer("-", " ", "_", " ")
+	slugJoiner     = strings.NewReplacer("-", "", "_", "")
+)
+
+// ReleasedSlugModel records that a short URL stopped being used, either
+// because its short was renamed or because it was purged from the trash.
+type ReleasedSlugModel struct {
+	ID         uint           `gorm:"primaryKey"`
+	DomainID   types.DomainId `gorm:"not null;index:idx_released_slugs_domain_short_url,priority:1"`
+	ShortUrl   string         `gorm:"not null;index:idx_released_slugs_domain_short_url,priority:2"`
+	UserID     types.UserId   `gorm:"not null"`
+	ReleasedAt time.Time      `gorm:"not null;index"`
+}
+
+// IRoleLookup finds the role of a user. It is implemented by the user
+// feature; without one every user is held to the regular minimum length.
+type IRoleLookup interface {
+	UserRole(ctx context.Context, id types.UserId) (string, error)
+}
+
+// SlugPolicy decides which custom short URLs a user may take.
+type SlugPolicy struct {
+	// Reserved holds words nobody may take, on top of the paths the
+	// application serves itself.
+	Reserved *wordlist.List
+	// Profanity holds words no short URL may contain anywhere.
+	Profanity *wordlist.List
+	// Trademarks holds names no short URL may use as one of its words, as in
+	// "acme" or "acme-sale" but not "acmesale".
+	Trademarks *wordlist.List
+	// MinLength applies to regular users and AdminMinLength to admins.
+	MinLength      int
+	AdminMinLength int
+	// Cooldown is how long a released short URL can only be taken again by
+	// the user who held it.
+	Cooldown time.Duration
+	Roles    IRoleLookup
+}
+
+// DefaultSlugPolicy leaves short URLs of one to three characters to admins
+// and keeps released short URLs from other users for DefaultSlugCooldown.
+func DefaultSlugPolicy() *SlugPolicy {
+	return &SlugPolicy{
+		MinLength:      DefaultSlugMinLength,
+		AdminMinLength: DefaultSlugAdminMinLength,
+		Cooldown:       DefaultSlugCooldown,
+	}
+}
+
+// WithSlugPolicy replaces the default slug policy.
+func WithSlugPolicy(policy *SlugPolicy) ShortServiceOption {
+	return func(s *shortService) {
+		if policy != nil {
+			s.Slugs = policy
+		}
+	}
+}
+
+func validateCustomSlug(slug string) error {
+	if !slugRegexp.MatchString(slug) {
+		return fmt.Errorf("%w: %q", ErrInvalidCustomShortUrl, slug)
+	}
+	return nil
+}
+
+func isReservedSlug(slug string) bool {
+	_, ok := reservedSlugs[strings.ToLower(slug)]
+	return ok
+}
+
+// checkWords rejects slug when it is reserved or contains a profane word or
+// a trademark. Generated codes go through it as well.
+func (p *SlugPolicy) checkWords(slug string) error {
+	if isReservedSlug(slug) || p.Reserved.Has(slug) {
+		return fmt.Errorf("%w: %q", ErrReservedShortUrl, slug)
+	}
+
+	joined := strings.ToLower(slugJoiner.Replace(slug))
+	for _, word := range p.Profanity.Entries() {
+		if strings.Contains(joined, word) {
+			return fmt.Errorf("%w: %q", ErrProfaneShortUrl, slug)
+		}
+	}
+
+	words := append(strings.Fields(slugSeparators.Replace(slug)), joined)
+	for _, word := range words {
+		if p.Trademarks.Has(word) {
+			return fmt.Errorf("%w: %q", ErrTrademarkShortUrl, slug)
+		}
+	}
+	return nil
+}
+
+// minLength returns the shortest custom short URL owner may take. Users whose
+// role cannot be looked up are held to the regular minimum.
+func (p *SlugPolicy) minLength(ctx context.Context, owner types.UserId) int {
+	if p.Roles != nil {
+		if role, err := p.Roles.UserRole(ctx, owner); err == nil && role == "admin" {
+			return p.AdminMinLength
+		}
+	}
+	return p.MinLength
+}
+
+// checkCustomSlug runs a custom short URL for owner through the slug policy:
+// its format, the word lists, the minimum length for the owner's role and
+// the cooldown of recently released short URLs.
+func (s *shortService) checkCustomSlug(ctx context.Context, owner types.UserId, domainID types.DomainId, slug string) error {
+	if err := validateCustomSlug(slug); err != nil {
+		return err
+	}
+	if err := s.Slugs.checkWords(slug); err != nil {
+		return err
+	}
+	if min := s.Slugs.minLength(ctx, owner); len(slug) < min {
+		return fmt.Errorf("%w: %q is shorter than %d characters", ErrShortUrlTooShort, slug, min)
+	}
+	return s.checkSlugCooldown(ctx, owner, domainID, slug)
+}
+
+// checkSlugCooldown refuses a short URL that was released less than the
+// cooldown ago by someone other than owner.
+func (s *shortService) checkSlugCooldown(ctx context.Context, owner types.UserId, domainID types.DomainId, slug string) error {
+	if s.Slugs.Cooldown <= 0 {
+		return nil
+	}
+	release, err := s.Repository.GetRelease(ctx, domainID, slug, time.Now().Add(-s.Slugs.Cooldown))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrShortenFailed, err)
+	}
+	if release != nil && release.UserID != owner {
+		return fmt.Errorf("%w: %s until %s", ErrShortUrlCoolingDown, slug,
+			release.ReleasedAt.Add(s.Slugs.Cooldown).Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package shortener
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+)
+
+// DefaultTrashRetention is how long deleted shorts and users stay in the trash
+// when TRASH_RETENTION is not set.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// GetTrash returns the trashed shorts of userID, or of every user when userID
+// is nil, most recently deleted first.
+func (s *shortService) GetTrash(ctx context.Context, userID *types.UserId) ([]ShortModel, error) {
+	return s.Repository.ListTrash(ctx, userID)
+}
+
+// RestoreURL takes a short out of the trash. Its short URL was never given to
+// anyone else, so it resolves again right away.
+func (s *shortService) RestoreURL(ctx context.Context, id types.ShortId) (ShortModel, error) {
+	if err := s.Repository.Restore(ctx, id); err != nil {
+		return ShortModel{}, err
+	}
+	return s.Repository.GetById(ctx, id)
+}
+
+// RestoreURLForUser restores the short only when it belongs to userID.
+func (s *shortService) RestoreURLForUser(ctx context.Context, userID types.UserId, id types.ShortId) (ShortModel, error) {
+	short, err := s.Repository.GetDeleted(ctx, id)
+	if err != nil {
+		return ShortModel{}, err
+	}
+	if short.UserID != userID {
+		return ShortModel{}, fmt.Errorf("%w: %d", ErrShortNotOwned, id)
+	}
+	return s.RestoreURL(ctx, id)
+}
+
+// PurgeTrash deletes the shorts that have been in the trash for longer than
+// retention for good and returns how many were purged. Their short URLs are
+// freed, but only to their previous owners until the slug cooldown has
+// passed. Releases older than the cooldown are forgotten on the way.
+func (s *shortService) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	purged, err := s.Repository.Purge(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Repository.DeleteReleases(ctx, time.Now().Add(-s.Slugs.Cooldown)); err != nil {
+		return purged, fmt.Errorf("%w: %v", ErrShortDeleteFail, err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,117 @@
+package shortener
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+// variantCookieTTL is how long a visitor keeps their A/B variant.
+const variantCookieTTL = 30 * 24 * time.Hour
+
+func (s ShortModel) variant(id types.VariantId) (VariantModel, bool) {
+	if id == 0 {
+		return VariantModel{}, false
+	}
+	for _, variant := range s.Variants {
+		if variant.ID == id {
+			return variant, true
+		}
+	}
+	return VariantModel{}, false
+}
+
+// pickVariant returns the variant roll falls on when the variants are laid
+// end to end by weight. roll must be in [0, the sum of the weights).
+func (s ShortModel) pickVariant(roll int) types.VariantId {
+	for _, variant := range s.Variants {
+		if roll < variant.Weight {
+			return variant.ID
+		}
+		roll -= variant.Weight
+	}
+	return 0
+}
+
+func (s ShortModel) variantWeight() int {
+	total := 0
+	for _, variant := range s.Variants {
+		total += variant.Weight
+	}
+	return total
+}
+
+func variantCookieName(id types.ShortId) string {
+	return fmt.Sprintf("shorty_v%d", id)
+}
+
+// assignVariant returns the A/B variant of the visitor of c. Returning
+// visitors keep the variant in their cookie; new visitors are given one by
+// weight, which pinVariant pins them to once it decided where they went.
+func assignVariant(c *fiber.Ctx, short ShortModel) types.VariantId {
+	total := short.variantWeight()
+	if total <= 0 {
+		return 0
+	}
+
+	name := variantCookieName(short.ID)
+	if id, err := strconv.ParseUint(c.Cookies(name), 10, 64); err == nil {
+		if variant, ok := short.variant(types.VariantId(id)); ok {
+			return variant.ID
+		}
+	}
+
+	return short.pickVariant(rand.IntN(total))
+}
+
+// pinVariant sets the cookie that keeps the visitor of c on variant id. It is
+// only called with the variant Target returned, so a visitor sent elsewhere
+// by a redirect rule or a scheduled destination is not pinned to a variant
+// they never saw.
+func pinVariant(c *fiber.Ctx, shortID types.ShortId, id types.VariantId) {
+	value := strconv.FormatUint(uint64(id), 10)
+	name := variantCookieName(shortID)
+	if id == 0 || c.Cookies(name) == value {
+		return
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(variantCookieTTL.Seconds()),
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// validateVariants rejects a single variant, which would not split anything.
+func validateVariants(variants []VariantRequest) error {
+	if len(variants) == 1 {
+		return fmt.Errorf("%w: at least two variants are needed", ErrInvalidVariants)
+	}
+	return nil
+}
+
+func newVariantModels(variants []VariantRequest) []VariantModel {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	models := make([]VariantModel, 0, len(variants))
+	for i, variant := range variants {
+		name := variant.Name
+		if name == "" {
+			name = string(rune('a' + i))
+		}
+		weight := variant.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		models = append(models, VariantModel{Name: name, Url: variant.Url, Weight: weight})
+	}
+	return models
+}
@@ -1,6 +1,10 @@
 package user
 
-import "github.com/Kalmera74/Shorty/internal/features/shortener"
+import (
+	"time"
+
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+)
 
 type UserRegisterRequest struct {
 	UserName string `json:"user_name" validate:"required,min=3,max=10" `
@@ -27,11 +31,17 @@ type UserResponse struct {
 	UserName string                    `json:"user_name"`
 	Email    string                    `json:"email"`
 	Shorts   []shortener.ShortResponse `json:"shorts"`
+	// DeletedAt is when the user was moved to the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
+
+// PaginatedUsersResponse is one page of users. Total and TotalPages are only
+// set when the exact count was requested.
 type PaginatedUsersResponse struct {
 	Page       int            `json:"page"`
 	PageSize   int            `json:"pageSize"`
-	Total      int            `json:"total"`
-	TotalPages int            `json:"totalPages"`
+	Total      *int           `json:"total,omitempty"`
+	TotalPages *int           `json:"totalPages,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 	Data       []UserResponse `json:"data"`
 }
@@ -1,10 +1,12 @@
 package user
 
 import (
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/Kalmera74/Shorty/pkg/auth"
 	"github.com/go-playground/validator/v10"
@@ -26,22 +28,21 @@ func NewUserHandler(service IUserService) *UserHandler {
 // @Description Get all registered users (paginated)
 // @Tags users
 // @Produce json
-// @Param page query int false "Page number" default(1)
+// @Param page query int false "Page number, ignored when cursor is set" default(1)
 // @Param pageSize query int false "Number of items per page" default(10)
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Param count query bool false "Include the exact total, on by default only without cursor"
 // @Success 200 {object} PaginatedUsersResponse
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/v1/users [get]
 func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
-	page := c.QueryInt("page", 1)
-	pageSize := c.QueryInt("pageSize", 10)
-	if page < 1 {
-		page = 1
-	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+	params, page, err := pagination.FromQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	allUsers, total, err := h.service.GetAllUsers(c.Context(), page, pageSize)
+	allUsers, result, err := h.service.GetAllUsers(c.Context(), params)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -59,9 +60,10 @@ func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
 	// Build paginated response
 	return c.JSON(PaginatedUsersResponse{
 		Page:       page,
-		PageSize:   pageSize,
-		Total:      total,
-		TotalPages: (total + pageSize - 1) / pageSize,
+		PageSize:   params.Limit,
+		Total:      result.Total,
+		TotalPages: result.TotalPages(params.Limit),
+		NextCursor: result.NextCursor,
 		Data:       users,
 	})
 }
@@ -93,11 +95,7 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	if len(userModel.Shorts) > 0 {
 		shortsResponse = make([]shortener.ShortResponse, 0, len(userModel.Shorts))
 		for _, shortModel := range userModel.Shorts {
-			shortsResponse = append(shortsResponse, shortener.ShortResponse{
-				Id:          shortModel.ID,
-				OriginalUrl: shortModel.OriginalUrl,
-				ShortUrl:    shortModel.ShortUrl,
-			})
+			shortsResponse = append(shortsResponse, shortener.NewShortResponse(shortModel))
 		}
 	}
 
@@ -215,7 +213,7 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Remove a user by ID
+// @Description Moves a user and their shorts to the trash, from where they can be restored until they are purged
 // @Tags users
 // @Param id path int true "User ID"
 // @Success 204
@@ -235,3 +233,56 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+// GetDeletedUsers godoc
+// @Summary List the trashed users
+// @Description Trashed users are purged for good, with their shorts, once the trash retention period has passed
+// @Tags users
+// @Produce json
+// @Success 200 {array} UserResponse
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/users/trash [get]
+func (h *UserHandler) GetDeletedUsers(c *fiber.Ctx) error {
+	deletedUsers, err := h.service.GetDeletedUsers(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	users := make([]UserResponse, 0, len(deletedUsers))
+	for _, user := range deletedUsers {
+		deletedAt := user.DeletedAt.Time
+		users = append(users, UserResponse{
+			Id:        uint(user.ID),
+			UserName:  user.UserName,
+			Email:     user.Email,
+			DeletedAt: &deletedAt,
+		})
+	}
+	return c.JSON(users)
+}
+
+// RestoreUser godoc
+// @Summary Restore a trashed user
+// @Description Takes a user out of the trash, together with the shorts that were deleted with them
+// @Tags users
+// @Param id path int true "User ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID"})
+	}
+
+	if err := h.service.RestoreUser(c.Context(), types.UserId(id)); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -5,13 +5,16 @@ import (
 
 	"github.com/Kalmera74/Shorty/internal/features/shortener"
 	"github.com/Kalmera74/Shorty/internal/types"
+	"gorm.io/gorm"
 )
 
 type UserModel struct {
-	ID           types.UserId           `gorm:"primaryKey"`
-	CreatedAt    time.Time              `json:"created_at"`
-	UpdatedAt    time.Time              `json:"updated_at"`
-	DeletedAt    time.Time              `json:"deleted_at,omitempty" gorm:"index"`
+	ID        types.UserId `gorm:"primaryKey"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	// DeletedAt is set while the user, and the shorts deleted with them, are
+	// in the trash.
+	DeletedAt    gorm.DeletedAt         `json:"deleted_at,omitempty" gorm:"index"`
 	UserName     string                 `validate:"required,min=3,max=30"`
 	Email        string                 `validate:"required,email"`
 	PasswordHash string                 `validate:"required"`
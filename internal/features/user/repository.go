@@ -4,17 +4,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/Kalmera74/Shorty/internal/features/domain"
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"gorm.io/gorm"
 )
 
 type IUserRepository interface {
-	GetAll(ctx context.Context, offset, limit int) ([]UserModel, int, error)
+	GetAll(ctx context.Context, params pagination.Params) ([]UserModel, pagination.Page, error)
 	Get(ctx context.Context, id types.UserId) (UserModel, error)
 	Add(ctx context.Context, user UserModel) (UserModel, error)
 	Update(ctx context.Context, id types.UserId, user UserModel) error
 	Delete(ctx context.Context, id types.UserId) error
+	ListTrash(ctx context.Context) ([]UserModel, error)
+	Restore(ctx context.Context, id types.UserId) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
 	GetByEmail(ctx context.Context, email string) (UserModel, error)
 }
 
@@ -26,27 +33,26 @@ func NewUserRepository(db *gorm.DB) IUserRepository {
 	return &postgresUserRepository{db}
 }
 
-func (s *postgresUserRepository) GetAll(ctx context.Context, offset, limit int) ([]UserModel, int, error) {
+func (s *postgresUserRepository) GetAll(ctx context.Context, params pagination.Params) ([]UserModel, pagination.Page, error) {
 	var users []UserModel
-	var total int64
 
-	if err := s.db.WithContext(ctx).Model(&UserModel{}).Count(&total).Error; err != nil {
-		return nil, -1, fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	total, err := params.Count(s.db.WithContext(ctx).Model(&UserModel{}))
+	if err != nil {
+		return nil, pagination.Page{}, fmt.Errorf("%w: %v", ErrUserNotFound, err)
 	}
 
 	if err := s.db.WithContext(ctx).
-		Order("id DESC").
-		Offset(offset).
-		Limit(limit).
+		Scopes(params.Scope("id")).
 		Find(&users).Error; err != nil {
-		return nil, -1, fmt.Errorf("%w: %v", ErrUserNotFound, err)
+		return nil, pagination.Page{}, fmt.Errorf("%w: %v", ErrUserNotFound, err)
 	}
 
 	if len(users) == 0 {
-		return nil, -1, fmt.Errorf("%w", ErrUserNotFound)
+		return nil, pagination.Page{}, fmt.Errorf("%w", ErrUserNotFound)
 	}
 
-	return users, int(total), nil
+	users, next := pagination.Trim(users, params.Limit, func(u UserModel) uint { return uint(u.ID) })
+	return users, pagination.Page{NextCursor: next, Total: total}, nil
 }
 
 func (s *postgresUserRepository) Get(ctx context.Context, id types.UserId) (UserModel, error) {
@@ -103,12 +109,105 @@ func (s *postgresUserRepository) Update(ctx context.Context, id types.UserId, re
 
 	return nil
 }
+
+// Delete moves a user, their shorts and their domains to the trash. The
+// shorts get the same deletion time as the user, which is how Restore tells
+// them apart from shorts the user had deleted before.
 func (s *postgresUserRepository) Delete(ctx context.Context, id types.UserId) error {
-	result := s.db.WithContext(ctx).Delete(&UserModel{}, id)
+	now := time.Now()
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&UserModel{}).
+			Where("id = ? AND deleted_at IS NULL", id).
+			Update("deleted_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("could not delete user %d. Reason: %v", id, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w, %v", ErrUserNotFound, id)
+		}
+
+		for _, model := range []any{&shortener.ShortModel{}, &domain.DomainModel{}} {
+			if err := tx.Unscoped().Model(model).
+				Where("user_id = ? AND deleted_at IS NULL", id).
+				Update("deleted_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("%w, %v", ErrUserNotFound, id)
+// ListTrash returns the trashed users, most recently deleted first.
+func (s *postgresUserRepository) ListTrash(ctx context.Context) ([]UserModel, error) {
+	var users []UserModel
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&users).Error; err != nil {
+		return nil, err
 	}
+	return users, nil
+}
 
-	return nil
+// Restore takes a user out of the trash together with the shorts and domains
+// that were deleted with them.
+func (s *postgresUserRepository) Restore(ctx context.Context, id types.UserId) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var u UserModel
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL").First(&u, id)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w, %v is not in the trash", ErrUserNotFound, id)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		for _, model := range []any{&shortener.ShortModel{}, &domain.DomainModel{}} {
+			if err := tx.Unscoped().Model(model).
+				Where("user_id = ? AND deleted_at = ?", id, u.DeletedAt.Time).
+				Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Model(&u).Update("deleted_at", nil).Error
+	})
+}
+
+// Purge deletes the users trashed before deletedBefore for good, with all of
+// their shorts, tags, folders, campaigns and domains, and returns how many
+// users there were. Their verified hosts can be claimed again afterwards. The shorts' clicks, routing and history go with them through
+// the ON DELETE CASCADE foreign keys. The short URLs are recorded as
+// released, so they stay out of reach of other users for the slug cooldown.
+func (s *postgresUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	var ids []types.UserId
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&UserModel{}).
+			Where("deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Exec(
+			"INSERT INTO released_slug_models (domain_id, short_url, user_id, released_at) "+
+				"SELECT domain_id, short_url, user_id, ? FROM short_models WHERE user_id IN ?", time.Now(), ids,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM short_tags WHERE short_model_id IN (SELECT id FROM short_models WHERE user_id IN ?)", ids).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&shortener.ShortModel{}, &shortener.TagModel{}, &shortener.FolderModel{}, &shortener.CampaignModel{}, &domain.DomainModel{}} {
+			if err := tx.Unscoped().Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&UserModel{}, ids).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not purge users. Reason: %v", err)
+	}
+	return len(ids), nil
 }
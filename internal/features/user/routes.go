@@ -13,7 +13,9 @@ func RegisterRoutes(app *fiber.App, handler *UserHandler) {
 
 	users := api.Group("/users", middleware.Authenticate(), middleware.Authorize("admin"))
 	users.Get("/", handler.GetAllUsers)
+	users.Get("/trash", handler.GetDeletedUsers)
 	users.Get("/:id", handler.GetUser)
 	users.Put("/:id", handler.UpdateUser)
 	users.Delete("/:id", handler.DeleteUser)
+	users.Post("/:id/restore", handler.RestoreUser)
 }
@@ -4,36 +4,62 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
+	caching "github.com/Kalmera74/Shorty/pkg/cache"
 	"github.com/Kalmera74/Shorty/pkg/security"
 )
 
 type IUserService interface {
-	GetAllUsers(ctx context.Context, page, pageSize int) ([]UserModel, int, error)
+	GetAllUsers(ctx context.Context, params pagination.Params) ([]UserModel, pagination.Page, error)
 	GetUser(ctx context.Context, id types.UserId) (UserModel, error)
 	CreateUser(ctx context.Context, req UserRegisterRequest) (UserModel, error)
 	UpdateUser(ctx context.Context, id types.UserId, req UserUpdateRequest) error
 	DeleteUser(ctx context.Context, id types.UserId) error
 	VerifyCredentials(ctx context.Context, email, password string) (*UserModel, error)
 	GetByEmail(ctx context.Context, email string) (*UserModel, error)
+	UserName(ctx context.Context, id types.UserId) (string, error)
+	UserRole(ctx context.Context, id types.UserId) (string, error)
+	GetDeletedUsers(ctx context.Context) ([]UserModel, error)
+	RestoreUser(ctx context.Context, id types.UserId) error
+	PurgeTrash(ctx context.Context, retention time.Duration) (int, error)
 }
 type userService struct {
 	Repository IUserRepository
+	// ShortCache is the redirect cache of the shortener, cleared of a
+	// user's shorts when the user is deleted.
+	ShortCache caching.ICacher
 }
 
-func NewUserService(s IUserRepository) IUserService {
-	return &userService{s}
+// UserServiceOption customises the service returned by NewUserService.
+type UserServiceOption func(*userService)
+
+// WithShortCache lets DeleteUser drop the user's shorts from the redirect
+// cache, so they stop resolving at once.
+func WithShortCache(cacher caching.ICacher) UserServiceOption {
+	return func(s *userService) {
+		s.ShortCache = cacher
+	}
 }
 
-func (s *userService) GetAllUsers(ctx context.Context, page, pageSize int) ([]UserModel, int, error) {
-	offset := (page - 1) * pageSize
-	users, total, err := s.Repository.GetAll(ctx, offset, pageSize)
+func NewUserService(s IUserRepository, opts ...UserServiceOption) IUserService {
+	service := &userService{Repository: s}
+	for _, opt := range opts {
+		opt(service)
+	}
+	return service
+}
+
+func (s *userService) GetAllUsers(ctx context.Context, params pagination.Params) ([]UserModel, pagination.Page, error) {
+	users, page, err := s.Repository.GetAll(ctx, params)
 	if err != nil {
-		return nil, 0, fmt.Errorf("%w, %v", ErrUserNotFound, err)
+		return nil, pagination.Page{}, fmt.Errorf("%w, %v", ErrUserNotFound, err)
 	}
 
-	return users, total, nil
+	return users, page, nil
 }
 
 func (s *userService) GetUser(ctx context.Context, id types.UserId) (UserModel, error) {
@@ -98,12 +124,31 @@ func (s *userService) UpdateUser(ctx context.Context, id types.UserId, req UserU
 
 	return nil
 }
+
+// DeleteUser moves the user and their shorts to the trash. The user can no
+// longer log in, and their shorts are dropped from the redirect cache so they
+// stop resolving right away.
 func (s *userService) DeleteUser(ctx context.Context, id types.UserId) error {
 
+	var cached []string
+	if s.ShortCache != nil {
+		userModel, err := s.Repository.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("Could not delete user %d. Reason: %v", id, err.Error())
+		}
+		for _, short := range userModel.Shorts {
+			cached = append(cached, shortener.ShortCacheKey(short))
+		}
+	}
+
 	err := s.Repository.Delete(ctx, id)
 	if err != nil {
 		return fmt.Errorf("Could not delete user %d. Reason: %v", id, err.Error())
 	}
+
+	if len(cached) > 0 {
+		s.ShortCache.Delete(ctx, cached...)
+	}
 	return nil
 }
 func (s *userService) GetByEmail(ctx context.Context, email string) (*UserModel, error) {
@@ -129,3 +174,40 @@ func (s *userService) VerifyCredentials(ctx context.Context, email, password str
 
 	return nil, fmt.Errorf("%w", ErrInvalidCredentials)
 }
+
+// UserName returns the public name of a user, as shown on link previews.
+func (s *userService) UserName(ctx context.Context, id types.UserId) (string, error) {
+	userModel, err := s.GetUser(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return userModel.UserName, nil
+}
+
+// UserRole returns the role of a user, which decides how short a custom
+// short URL they may take.
+func (s *userService) UserRole(ctx context.Context, id types.UserId) (string, error) {
+	userModel, err := s.GetUser(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return userModel.Role, nil
+}
+
+// GetDeletedUsers returns the users in the trash, most recently deleted first.
+func (s *userService) GetDeletedUsers(ctx context.Context) ([]UserModel, error) {
+	return s.Repository.ListTrash(ctx)
+}
+
+// RestoreUser takes a user out of the trash, together with the shorts that
+// were deleted with them.
+func (s *userService) RestoreUser(ctx context.Context, id types.UserId) error {
+	return s.Repository.Restore(ctx, id)
+}
+
+// PurgeTrash deletes the users that have been in the trash for longer than
+// retention for good, with everything they owned. It returns how many were
+// purged.
+func (s *userService) PurgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	return s.Repository.Purge(ctx, time.Now().Add(-retention))
+}
@@ -2,23 +2,57 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/Kalmera74/Shorty/internal/features/shortener"
+	"github.com/Kalmera74/Shorty/internal/pagination"
 	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/Kalmera74/Shorty/pkg/security"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
+// --- Mock Cacher ---
+
+type MockCacher struct {
+	mock.Mock
+}
+
+func (m *MockCacher) Get(ctx context.Context, key string) (string, error) {
+	args := m.Called(key)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockCacher) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return m.Called(key, value, ttl).Error(0)
+}
+
+func (m *MockCacher) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		m.Called(key)
+	}
+	return nil
+}
+
+func (m *MockCacher) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	args := m.Called(key, ttl)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // --- Mock Repository ---
 type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) GetAll(ctx context.Context, offset, limit int) ([]UserModel, int, error) {
-	args := m.Called()
-	return args.Get(0).([]UserModel), args.Int(1), args.Error(2)
+func (m *MockUserRepository) GetAll(ctx context.Context, params pagination.Params) ([]UserModel, pagination.Page, error) {
+	args := m.Called(params)
+	return args.Get(0).([]UserModel), args.Get(1).(pagination.Page), args.Error(2)
 }
 
 func (m *MockUserRepository) Get(ctx context.Context, id types.UserId) (UserModel, error) {
@@ -41,6 +75,21 @@ func (m *MockUserRepository) Delete(ctx context.Context, id types.UserId) error
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ListTrash(ctx context.Context) ([]UserModel, error) {
+	args := m.Called()
+	return args.Get(0).([]UserModel), args.Error(1)
+}
+
+func (m *MockUserRepository) Restore(ctx context.Context, id types.UserId) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	args := m.Called(deletedBefore)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (UserModel, error) {
 	args := m.Called(email)
 	return args.Get(0).(UserModel), args.Error(1)
@@ -49,17 +98,20 @@ func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (User
 // --- Tests ---
 func TestGetAllUsers_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	mockRepo.On("GetAll").Return([]UserModel{
-		{ID: 1, UserName: "alice", Email: "alice@test.com"},
-	}, 1, nil)
+	params := pagination.Params{Limit: 1}
+	mockRepo.On("GetAll", params).Return([]UserModel{
+		{ID: 2, UserName: "alice", Email: "alice@test.com"},
+	}, pagination.Page{NextCursor: pagination.EncodeCursor(2)}, nil)
 
 	svc := NewUserService(mockRepo)
 
-	users, _, err := svc.GetAllUsers(nil, 1, 1)
+	users, page, err := svc.GetAllUsers(nil, params)
 
 	assert.NoError(t, err)
 	assert.Len(t, users, 1)
 	assert.Equal(t, "alice", users[0].UserName)
+	assert.Equal(t, pagination.EncodeCursor(2), page.NextCursor)
+	assert.Nil(t, page.Total)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -74,6 +126,18 @@ func TestGetUser_NotFound(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestUserName(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("Get", types.UserId(2)).Return(UserModel{ID: 2, UserName: "alice"}, nil)
+
+	svc := NewUserService(mockRepo)
+
+	name, err := svc.UserName(context.Background(), types.UserId(2))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", name)
+}
+
 func TestCreateUser_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
 	req := UserRegisterRequest{
@@ -128,3 +192,127 @@ func TestVerifyCredentials_InvalidPassword(t *testing.T) {
 	assert.Nil(t, usr)
 	assert.EqualError(t, err, "invalid credentials")
 }
+
+func TestRestoreUser_NotInTrash(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockRepo.On("Restore", types.UserId(4)).Return(ErrUserNotFound)
+
+	svc := NewUserService(mockRepo)
+	err := svc.RestoreUser(context.Background(), 4)
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	retention := 7 * 24 * time.Hour
+	mockRepo.On("Purge", mock.MatchedBy(func(before time.Time) bool {
+		return before.Sub(time.Now().Add(-retention)).Abs() < time.Minute
+	})).Return(2, nil)
+
+	svc := NewUserService(mockRepo)
+	purged, err := svc.PurgeTrash(context.Background(), retention)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteUser_DropsCachedShorts(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockCache := new(MockCacher)
+	shorts := []shortener.ShortModel{
+		{ShortUrl: "abcd"},
+		{ShortUrl: "sale", DomainID: 3},
+	}
+	mockRepo.On("Get", types.UserId(5)).Return(UserModel{ID: 5, Shorts: shorts}, nil)
+	mockRepo.On("Delete", types.UserId(5)).Return(nil)
+	for _, short := range shorts {
+		mockCache.On("Delete", shortener.ShortCacheKey(short)).Return()
+	}
+
+	svc := NewUserService(mockRepo, WithShortCache(mockCache))
+	err := svc.DeleteUser(context.Background(), 5)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+// --- Dry Run Repository ---
+
+// dryRunPool stands in for the database connection of a dry run, which never
+// sends anything but still opens transactions.
+type dryRunPool struct{}
+
+func (*dryRunPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("dry run")
+}
+
+func (*dryRunPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (p *dryRunPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return p, nil
+}
+
+func (*dryRunPool) Commit() error   { return nil }
+func (*dryRunPool) Rollback() error { return nil }
+
+// dryRunRepository returns a repository that records the statements it
+// writes instead of running them. Queries find the trashed user userID.
+func dryRunRepository(t *testing.T, userID types.UserId) (IUserRepository, *[]string) {
+	dbConn, err := gorm.Open(postgres.New(postgres.Config{Conn: &dryRunPool{}}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	require.NoError(t, err)
+
+	var statements []string
+	record := func(tx *gorm.DB) {
+		statements = append(statements, tx.Statement.SQL.String())
+		tx.RowsAffected = 1
+	}
+	callbacks := dbConn.Callback()
+	require.NoError(t, callbacks.Update().After("gorm:update").Register("test:record", record))
+	require.NoError(t, callbacks.Delete().After("gorm:delete").Register("test:record", record))
+	require.NoError(t, callbacks.Raw().After("gorm:raw").Register("test:record", record))
+	require.NoError(t, callbacks.Query().After("gorm:query").Register("test:find", func(tx *gorm.DB) {
+		switch dest := tx.Statement.Dest.(type) {
+		case *[]types.UserId:
+			*dest = []types.UserId{userID}
+		case *UserModel:
+			*dest = UserModel{ID: userID, DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}}
+		}
+		tx.RowsAffected = 1
+	}))
+	return NewUserRepository(dbConn), &statements
+}
+
+func TestTrashedUserDomains(t *testing.T) {
+	repo, statements := dryRunRepository(t, 5)
+	svc := NewUserService(repo)
+
+	require.NoError(t, svc.DeleteUser(context.Background(), 5))
+	assert.Contains(t, *statements, `UPDATE "domain_models" SET "deleted_at"=$1,"updated_at"=$2 WHERE user_id = $3 AND deleted_at IS NULL`)
+
+	*statements = nil
+	require.NoError(t, svc.RestoreUser(context.Background(), 5))
+	assert.Contains(t, *statements, `UPDATE "domain_models" SET "deleted_at"=$1,"updated_at"=$2 WHERE user_id = $3 AND deleted_at = $4`)
+
+	*statements = nil
+	purged, err := svc.PurgeTrash(context.Background(), time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+	assert.Contains(t, *statements, `DELETE FROM "domain_models" WHERE user_id IN ($1)`)
+}
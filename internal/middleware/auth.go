@@ -1,12 +1,17 @@
 package middleware
 
 import (
+	"errors"
+
+	"github.com/Kalmera74/Shorty/internal/types"
 	"github.com/Kalmera74/Shorty/pkg/auth"
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var ErrInvalidTokenClaims = errors.New("invalid token claims")
+
 func Authenticate() fiber.Handler {
 	return jwtware.New(jwtware.Config{
 		SigningKey: jwtware.SigningKey{Key: []byte(auth.JwtSecretKey)},
@@ -47,3 +52,26 @@ func Authorize(requiredRole string) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// CurrentUser returns the user id and role from the claims that
+// auth.GenerateJWTToken put in the token validated by Authenticate.
+func CurrentUser(c *fiber.Ctx) (types.UserId, string, error) {
+	userToken, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return 0, "", ErrInvalidTokenClaims
+	}
+
+	claims, ok := userToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, "", ErrInvalidTokenClaims
+	}
+
+	// JSON numbers decode as float64.
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID < 1 {
+		return 0, "", ErrInvalidTokenClaims
+	}
+
+	role, _ := claims["role"].(string)
+	return types.UserId(userID), role, nil
+}
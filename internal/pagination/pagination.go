@@ -0,0 +1,138 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// Params selects one page of a listing ordered by id, newest first.
+type Params struct {
+	// After is the id of the last row of the previous page, the page holds
+	// the rows with a smaller id. 0 starts at the newest row.
+	After uint
+	// Offset skips rows for clients that still page by number. It is ignored
+	// when After is set.
+	Offset int
+	Limit  int
+	// CountTotal asks for the exact number of rows, which costs a COUNT(*)
+	// over the whole table.
+	CountTotal bool
+}
+
+// Page tells the caller how to continue a listing.
+type Page struct {
+	// NextCursor is passed back as the cursor query parameter to fetch the
+	// following page. It is empty on the last page.
+	NextCursor string
+	// Total is the exact number of rows, nil unless Params.CountTotal was set.
+	Total *int
+}
+
+// EncodeCursor turns the id of the last row of a page into an opaque cursor.
+func EncodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(id), 10)))
+}
+
+// DecodeCursor returns the id a cursor made by EncodeCursor points after.
+func DecodeCursor(cursor string) (uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return uint(id), nil
+}
+
+// FromQuery reads the page, pageSize, cursor and count query parameters. The
+// total is counted by default only for page numbered requests, so cursor
+// clients do not pay for it unless they ask. The returned page number is 1
+// for cursor requests.
+func FromQuery(c *fiber.Ctx) (Params, int, error) {
+	page := c.QueryInt("page", 1)
+	pageSize := c.QueryInt("pageSize", DefaultPageSize)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = DefaultPageSize
+	}
+
+	cursor := c.Query("cursor")
+	params := Params{
+		Limit:      pageSize,
+		CountTotal: c.QueryBool("count", cursor == ""),
+	}
+
+	if cursor == "" {
+		params.Offset = (page - 1) * pageSize
+		return params, page, nil
+	}
+
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return Params{}, 0, err
+	}
+	params.After = after
+	return params, 1, nil
+}
+
+// Scope restricts a query to the page, newest first by column. It fetches one
+// row more than the limit so Trim can tell whether another page follows.
+func (p Params) Scope(column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if p.After > 0 {
+			db = db.Where(column+" < ?", p.After)
+		} else if p.Offset > 0 {
+			db = db.Offset(p.Offset)
+		}
+		return db.Order(column + " DESC").Limit(p.Limit + 1)
+	}
+}
+
+// Trim drops the extra row fetched by Scope and returns the cursor of the
+// following page, empty when rows was the last page.
+func Trim[T any](rows []T, limit int, id func(T) uint) ([]T, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	return rows, EncodeCursor(id(rows[limit-1]))
+}
+
+// Count runs the COUNT(*) for query when the caller asked for the total.
+func (p Params) Count(query *gorm.DB) (*int, error) {
+	if !p.CountTotal {
+		return nil, nil
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	count := int(total)
+	return &count, nil
+}
+
+// TotalPages returns the number of pages of pageSize rows, nil when the total
+// is unknown.
+func (p Page) TotalPages(pageSize int) *int {
+	if p.Total == nil || pageSize < 1 {
+		return nil
+	}
+	pages := (*p.Total + pageSize - 1) / pageSize
+	return &pages
+}
@@ -3,3 +3,11 @@ package types
 type UserId uint
 type ShortId uint
 type ClickId uint
+type DomainId uint
+type TagId uint
+type FolderId uint
+type DestinationId uint
+type RuleId uint
+type VariantId uint
+type HistoryId uint
+type CampaignId uint
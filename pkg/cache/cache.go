@@ -14,7 +14,8 @@ import (
 type ICacher interface {
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Get(ctx context.Context, key string) (string, error)
-	Delete(ctx context.Context, key string) error
+	Delete(ctx context.Context, keys ...string) error
+	Increment(ctx context.Context, key string, expiration time.Duration) (int64, error)
 }
 
 type RedisCacher struct {
@@ -56,10 +57,27 @@ func NewCacher() ICacher {
 func (r *RedisCacher) Set(ctx context.Context, key string, value interface{}, exp time.Duration) error {
 	return r.Client.Set(ctx, key, value, exp).Err()
 }
-func (r *RedisCacher) Delete(ctx context.Context, key string) error {
-	return r.Client.Del(ctx, key).Err()
+
+// Delete removes all keys in a single DEL so they disappear together.
+func (r *RedisCacher) Delete(ctx context.Context, keys ...string) error {
+	return r.Client.Del(ctx, keys...).Err()
 }
 func (r *RedisCacher) Get(ctx context.Context, key string) (string, error) {
 	get := r.Client.Get(ctx, key)
 	return get.Result()
 }
+
+// Increment atomically increments the integer stored at key. A positive
+// expiration is applied when the key is first created.
+func (r *RedisCacher) Increment(ctx context.Context, key string, exp time.Duration) (int64, error) {
+	val, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if val == 1 && exp > 0 {
+		if err := r.Client.Expire(ctx, key, exp).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return val, nil
+}
@@ -0,0 +1,71 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var ErrInvalidIP = errors.New("Invalid IP address")
+
+// ILocator finds the country an IP address is registered in.
+type ILocator interface {
+	// Country returns the upper-case ISO 3166-1 alpha-2 code of ip's
+	// country, or "" when the database does not know the address.
+	Country(ip string) (string, error)
+	Close() error
+}
+
+// MMDBLocator reads a MaxMind DB file such as GeoLite2-Country or
+// GeoLite2-City. Lookups are served from the memory-mapped file and never go
+// to the network.
+type MMDBLocator struct {
+	reader *maxminddb.Reader
+}
+
+// record holds the fields MMDBLocator reads from both the country and the
+// city databases.
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Open opens the MaxMind DB file at path.
+func Open(path string) (*MMDBLocator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open GeoIP database %s: %w", path, err)
+	}
+	return &MMDBLocator{reader: reader}, nil
+}
+
+func (l *MMDBLocator) Country(ip string) (string, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidIP, ip)
+	}
+
+	var result record
+	if err := l.reader.Lookup(addr, &result); err != nil {
+		return "", err
+	}
+
+	// Anycast and satellite ranges have no country of their own, only the
+	// country of the network that registered them.
+	code := result.Country.ISOCode
+	if code == "" {
+		code = result.RegisteredCountry.ISOCode
+	}
+	return strings.ToUpper(code), nil
+}
+
+func (l *MMDBLocator) Close() error {
+	return l.reader.Close()
+}
@@ -0,0 +1,123 @@
+package linkcheck
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/safehttp"
+)
+
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "ShortyBot/1.0 (+link health check)"
+)
+
+// Result is the outcome of one check of a URL.
+type Result struct {
+	// Status is the HTTP status of the final response after redirects, 0
+	// when no response arrived.
+	Status  int
+	Latency time.Duration
+	Err     error
+}
+
+// Healthy reports whether the URL answered with a status below 400.
+func (r Result) Healthy() bool {
+	return r.Err == nil && r.Status > 0 && r.Status < 400
+}
+
+// Checker tells whether URLs are reachable.
+type Checker struct {
+	Timeout   time.Duration
+	UserAgent string
+	// AllowPrivate lets the checker connect to loopback and private
+	// addresses, see safehttp.NewClient.
+	AllowPrivate bool
+
+	once   sync.Once
+	client *http.Client
+}
+
+// NewChecker returns a checker with the default timeout.
+func NewChecker() *Checker {
+	return &Checker{Timeout: DefaultTimeout, UserAgent: DefaultUserAgent}
+}
+
+// Check requests url with HEAD, and with GET when the server does not answer
+// HEAD properly, which many do not. The body of a GET response is not read.
+func (c *Checker) Check(ctx context.Context, url string) Result {
+	c.once.Do(func() {
+		c.client = safehttp.NewClient(c.Timeout, c.AllowPrivate)
+	})
+
+	start := time.Now()
+	status, err := c.do(ctx, http.MethodHead, url)
+	if err != nil || status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented || status == http.StatusForbidden {
+		start = time.Now()
+		status, err = c.do(ctx, http.MethodGet, url)
+	}
+	return Result{Status: status, Latency: time.Since(start), Err: err}
+}
+
+func (c *Checker) do(ctx context.Context, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Draining a little lets the connection be reused without downloading
+	// large pages.
+	_, _ = io.CopyN(io.Discard, resp.Body, 4<<10)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// HostLimiter spaces out requests to the same host so checking many links to
+// one site does not hammer it.
+type HostLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// NewHostLimiter allows one request per host every interval.
+func NewHostLimiter(interval time.Duration) *HostLimiter {
+	return &HostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// Wait blocks until a request to host may be sent, or ctx is done.
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/safehttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChecker() *Checker {
+	checker := NewChecker()
+	checker.AllowPrivate = true
+	return checker
+}
+
+func TestCheck_Head(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	result := newTestChecker().Check(context.Background(), server.URL)
+
+	assert.True(t, result.Healthy())
+	assert.Equal(t, http.StatusNoContent, result.Status)
+	assert.Equal(t, []string{http.MethodHead}, methods)
+}
+
+func TestCheck_FallsBackToGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	result := newTestChecker().Check(context.Background(), server.URL)
+
+	assert.True(t, result.Healthy())
+	assert.Equal(t, http.StatusOK, result.Status)
+}
+
+func TestCheck_Broken(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	result := newTestChecker().Check(context.Background(), server.URL)
+	assert.False(t, result.Healthy())
+	assert.Equal(t, http.StatusNotFound, result.Status)
+
+	result = NewChecker().Check(context.Background(), server.URL)
+	assert.False(t, result.Healthy())
+	assert.ErrorIs(t, result.Err, safehttp.ErrPrivateAddress)
+}
+
+func TestHostLimiter(t *testing.T) {
+	limiter := NewHostLimiter(50 * time.Millisecond)
+	ctx := context.Background()
+
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(ctx, "example.com"))
+	assert.NoError(t, limiter.Wait(ctx, "other.example"))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	assert.NoError(t, limiter.Wait(ctx, "EXAMPLE.com"))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, limiter.Wait(cancelled, "example.com"), context.Canceled)
+}
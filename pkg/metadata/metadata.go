@@ -0,0 +1,216 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/safehttp"
+	"golang.org/x/net/html"
+)
+
+const (
+	DefaultTimeout   = 5 * time.Second
+	DefaultMaxBytes  = 512 << 10
+	DefaultUserAgent = "ShortyBot/1.0 (+link preview)"
+	// maxFieldLength caps the stored length of the text fields.
+	maxFieldLength = 512
+)
+
+var (
+	ErrUnexpectedStatus = errors.New("Unexpected response status")
+	ErrNotHTML          = errors.New("Response is not an HTML page")
+)
+
+// Metadata is what a page says about itself in its <head>.
+type Metadata struct {
+	Title       string
+	Description string
+	SiteName    string
+	ImageUrl    string
+	FaviconUrl  string
+}
+
+// Fetcher downloads pages and reads their metadata. Only the first MaxBytes
+// of a page are read, and a whole fetch, redirects included, is given
+// Timeout.
+type Fetcher struct {
+	Timeout   time.Duration
+	MaxBytes  int64
+	UserAgent string
+	// AllowPrivate lets the fetcher connect to loopback and private
+	// addresses, see safehttp.NewClient.
+	AllowPrivate bool
+
+	client *http.Client
+}
+
+// NewFetcher returns a fetcher with the default limits.
+func NewFetcher() *Fetcher {
+	return &Fetcher{Timeout: DefaultTimeout, MaxBytes: DefaultMaxBytes, UserAgent: DefaultUserAgent}
+}
+
+func (f *Fetcher) httpClient() *http.Client {
+	if f.client == nil {
+		f.client = safehttp.NewClient(f.Timeout, f.AllowPrivate)
+	}
+	return f.client
+}
+
+// Fetch downloads the page at rawURL and returns its metadata. Relative image
+// and favicon URLs are resolved against the final URL after redirects, and
+// the favicon falls back to /favicon.ico.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.httpClient().Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Metadata{}, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, fmt.Errorf("%w: %s", ErrNotHTML, mediaType)
+	}
+
+	meta := parse(io.LimitReader(resp.Body, f.MaxBytes))
+	base := resp.Request.URL
+	meta.ImageUrl = resolve(base, meta.ImageUrl)
+	if meta.FaviconUrl == "" {
+		meta.FaviconUrl = "/favicon.ico"
+	}
+	meta.FaviconUrl = resolve(base, meta.FaviconUrl)
+	return meta, nil
+}
+
+// parse reads the <head> of a page. It stops at <body>, so the rest of the
+// page is never read.
+func parse(r io.Reader) Metadata {
+	var (
+		meta    Metadata
+		ogTitle string
+		ogDesc  string
+		inTitle bool
+		title   strings.Builder
+	)
+
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return finish(meta, ogTitle, ogDesc, title.String())
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" {
+				inTitle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				return finish(meta, ogTitle, ogDesc, title.String())
+			case "title":
+				inTitle = title.Len() == 0
+			case "meta":
+				attrs := attributes(tokenizer, hasAttr)
+				key := attrs["property"]
+				if key == "" {
+					key = attrs["name"]
+				}
+				content := attrs["content"]
+				switch strings.ToLower(key) {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDesc = content
+				case "og:site_name":
+					meta.SiteName = content
+				case "og:image":
+					meta.ImageUrl = content
+				case "description":
+					meta.Description = content
+				}
+			case "link":
+				attrs := attributes(tokenizer, hasAttr)
+				if meta.FaviconUrl == "" && isIconRel(attrs["rel"]) {
+					meta.FaviconUrl = attrs["href"]
+				}
+			}
+		}
+	}
+}
+
+// finish prefers the Open Graph title and description over the plain ones.
+func finish(meta Metadata, ogTitle, ogDesc, title string) Metadata {
+	meta.Title = title
+	if ogTitle != "" {
+		meta.Title = ogTitle
+	}
+	if ogDesc != "" {
+		meta.Description = ogDesc
+	}
+
+	meta.Title = clip(meta.Title)
+	meta.Description = clip(meta.Description)
+	meta.SiteName = clip(meta.SiteName)
+	return meta
+}
+
+func attributes(tokenizer *html.Tokenizer, more bool) map[string]string {
+	attrs := make(map[string]string)
+	for more {
+		var key, value []byte
+		key, value, more = tokenizer.TagAttr()
+		attrs[string(key)] = string(value)
+	}
+	return attrs
+}
+
+func isIconRel(rel string) bool {
+	for _, part := range strings.Fields(strings.ToLower(rel)) {
+		if part == "icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve makes ref absolute against base, dropping anything that is not an
+// http or https URL.
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+// clip collapses whitespace and cuts s to maxFieldLength runes.
+func clip(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > maxFieldLength {
+		s = string(runes[:maxFieldLength])
+	}
+	return s
+}
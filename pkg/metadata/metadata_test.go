@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Kalmera74/Shorty/pkg/safehttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFetcher() *Fetcher {
+	fetcher := NewFetcher()
+	fetcher.AllowPrivate = true
+	return fetcher
+}
+
+func TestFetch_OpenGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<!DOCTYPE html><html><head>
+<title>Plain   title</title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="Open Graph title">
+<meta property="og:site_name" content="Example">
+<meta property="og:image" content="/images/cover.png">
+<link rel="shortcut icon" href="/static/icon.png">
+</head><body><title>Not this one</title></body></html>`))
+	}))
+	defer server.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), server.URL+"/article")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Metadata{
+		Title:       "Open Graph title",
+		Description: "Plain description",
+		SiteName:    "Example",
+		ImageUrl:    server.URL + "/images/cover.png",
+		FaviconUrl:  server.URL + "/static/icon.png",
+	}, meta)
+}
+
+func TestFetch_FallsBackToTitleAndFavicon(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>\n  Hello\n  world </title></head></html>"))
+	}))
+	defer server.Close()
+
+	meta, err := newTestFetcher().Fetch(context.Background(), server.URL+"/old")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello world", meta.Title)
+	assert.Equal(t, server.URL+"/favicon.ico", meta.FaviconUrl)
+}
+
+func TestFetch_Limits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<title>Too late</title>"))
+		case "/huge":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1000) + "<title>Too far</title>"))
+		}
+	}))
+	defer server.Close()
+
+	fetcher := newTestFetcher()
+	fetcher.Timeout = 50 * time.Millisecond
+	fetcher.MaxBytes = 1024
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/missing")
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/image")
+	assert.ErrorIs(t, err, ErrNotHTML)
+
+	_, err = fetcher.Fetch(context.Background(), server.URL+"/slow")
+	assert.Error(t, err)
+
+	meta, err := fetcher.Fetch(context.Background(), server.URL+"/huge")
+	assert.NoError(t, err)
+	assert.Empty(t, meta.Title)
+}
+
+func TestFetch_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<title>Internal</title>"))
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher().Fetch(context.Background(), server.URL)
+
+	assert.ErrorIs(t, err, safehttp.ErrPrivateAddress)
+}
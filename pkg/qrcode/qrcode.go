@@ -0,0 +1,571 @@
+// Package qrcode encodes data as QR codes (ISO/IEC 18004) in byte mode and
+// renders them as PNG or SVG images.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level is the error correction level of a QR code. Higher levels survive
+// more damage at the cost of a larger symbol.
+type Level int
+
+const (
+	Low      Level = iota // recovers about 7% of codewords
+	Medium                // recovers about 15% of codewords
+	Quartile              // recovers about 25% of codewords
+	High                  // recovers about 30% of codewords
+)
+
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+var (
+	ErrDataTooLong  = errors.New("Data too long for a QR code")
+	ErrInvalidLevel = errors.New("Invalid error correction level")
+)
+
+// ParseLevel parses the one letter name of a level: L, M, Q or H.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrInvalidLevel, s)
+	}
+}
+
+// formatBits are the two bits identifying the level in the format information.
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// eccCodewordsPerBlock and numECCBlocks are indexed by level and version.
+var eccCodewordsPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var numECCBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Code is an encoded QR code symbol, a square grid of dark and light modules.
+type Code struct {
+	version  int
+	level    Level
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+// Encode encodes data in byte mode using the smallest version that fits at
+// the given error correction level.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, ErrInvalidLevel
+	}
+
+	version := minVersion
+	for ; version <= maxVersion; version++ {
+		if dataBitsNeeded(len(data), version) <= numDataCodewords(version, level)*8 {
+			break
+		}
+	}
+	if version > maxVersion {
+		return nil, fmt.Errorf("%w: %d bytes", ErrDataTooLong, len(data))
+	}
+
+	codewords := encodeData(data, version, level)
+	codewords = addECCAndInterleave(codewords, version, level)
+
+	code := newCode(version, level)
+	code.drawFunctionPatterns()
+	code.drawCodewords(codewords)
+	code.applyBestMask()
+
+	return code, nil
+}
+
+// Size returns the number of modules along one side, without the quiet zone.
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Version returns the QR version (1 to 40) the data was encoded with.
+func (c *Code) Version() int {
+	return c.version
+}
+
+// Dark reports whether the module at column x and row y is dark. Coordinates
+// outside the symbol are light.
+func (c *Code) Dark(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.size && y < c.size && c.modules[y][x]
+}
+
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	code := &Code{
+		version:  version,
+		level:    level,
+		size:     size,
+		modules:  make([][]bool, size),
+		function: make([][]bool, size),
+	}
+	for i := range code.modules {
+		code.modules[i] = make([]bool, size)
+		code.function[i] = make([]bool, size)
+	}
+	return code
+}
+
+// charCountBits is the length of the byte mode character count indicator.
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+func dataBitsNeeded(length, version int) int {
+	if length >= 1<<charCountBits(version) {
+		return 1 << 30
+	}
+	return 4 + charCountBits(version) + length*8
+}
+
+// numRawDataModules is the number of modules left for data and error
+// correction once the function patterns are drawn.
+func numRawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		numAlign := version/7 + 2
+		result -= (25*numAlign-10)*numAlign - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func numDataCodewords(version int, level Level) int {
+	return numRawDataModules(version)/8 - eccCodewordsPerBlock[level][version]*numECCBlocks[level][version]
+}
+
+// bitBuffer collects the bit stream before it is split into codewords.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// encodeData builds the padded data codewords: mode, length, payload,
+// terminator and the alternating pad bytes.
+func encodeData(data []byte, version int, level Level) []byte {
+	capacity := numDataCodewords(version, level) * 8
+
+	var bits bitBuffer
+	bits.append(0x4, 4)
+	bits.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return codewords
+}
+
+// addECCAndInterleave splits data into blocks, appends the Reed-Solomon
+// codewords of every block and interleaves the result.
+func addECCAndInterleave(data []byte, version int, level Level) []byte {
+	numBlocks := numECCBlocks[level][version]
+	blockECCLen := eccCodewordsPerBlock[level][version]
+	rawCodewords := numRawDataModules(version) / 8
+	numShortBlocks := numBlocks - rawCodewords%numBlocks
+	shortBlockLen := rawCodewords / numBlocks
+
+	divisor := reedSolomonDivisor(blockECCLen)
+	blocks := make([][]byte, numBlocks)
+	for i, k := 0, 0; i < numBlocks; i++ {
+		end := k + shortBlockLen - blockECCLen
+		if i >= numShortBlocks {
+			end++
+		}
+		block := append([]byte{}, data[k:end]...)
+		k = end
+
+		ecc := reedSolomonRemainder(block, divisor)
+		if i < numShortBlocks {
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+
+	result := make([]byte, 0, rawCodewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			// Short blocks carry a placeholder where long blocks have their
+			// extra data codeword.
+			if i != shortBlockLen-blockECCLen || j >= numShortBlocks {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFunctionPatterns() {
+	for i := 0; i < c.size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+
+	c.drawFinderPattern(3, 3)
+	c.drawFinderPattern(c.size-4, 3)
+	c.drawFinderPattern(3, c.size-4)
+
+	positions := alignmentPatternPositions(c.version)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// The corners overlap the finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignmentPattern(x, y)
+		}
+	}
+
+	// Reserve the format areas now, the real bits depend on the mask.
+	c.drawFormatBits(0)
+	c.drawVersion()
+}
+
+// drawFinderPattern draws a finder pattern and its separator centred on x, y.
+func (c *Code) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= c.size || yy >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func alignmentPatternPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	numAlign := version/7 + 2
+	step := (version*8 + numAlign*3 + 5) / (numAlign*4 - 4) * 2
+	size := version*4 + 17
+
+	result := make([]int, numAlign)
+	result[0] = 6
+	for i, pos := numAlign-1, size-7; i >= 1; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+	return result
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	data := c.level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(i))
+	}
+	c.setFunction(8, 7, bit(6))
+	c.setFunction(8, 8, bit(7))
+	c.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(i))
+	}
+	c.setFunction(8, c.size-8, true)
+}
+
+func (c *Code) drawVersion() {
+	if c.version < 7 {
+		return
+	}
+
+	rem := c.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, dark)
+		c.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, two columns at a
+// time from the bottom right corner, skipping the function modules.
+func (c *Code) drawCodewords(codewords []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(codewords)*8 {
+					c.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask. Applying the same mask
+// twice undoes it.
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !c.function[y][x] {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries all eight masks and keeps the one with the lowest
+// penalty score.
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		c.applyMask(mask)
+	}
+
+	c.applyMask(best)
+	c.drawFormatBits(best)
+}
+
+const (
+	penaltyRun     = 3
+	penaltyBlock   = 3
+	penaltyFinder  = 40
+	penaltyBalance = 10
+)
+
+// finderLike is the dark/light sequence of a finder pattern next to four
+// light modules, which the third penalty rule counts in both directions.
+var finderLike = [...][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func (c *Code) penalty() int {
+	result := 0
+
+	line := make([]bool, c.size)
+	for _, vertical := range []bool{false, true} {
+		for i := 0; i < c.size; i++ {
+			for j := 0; j < c.size; j++ {
+				if vertical {
+					line[j] = c.modules[j][i]
+				} else {
+					line[j] = c.modules[i][j]
+				}
+			}
+			result += linePenalty(line)
+		}
+	}
+
+	for y := 0; y < c.size-1; y++ {
+		for x := 0; x < c.size-1; x++ {
+			dark := c.modules[y][x]
+			if dark == c.modules[y][x+1] && dark == c.modules[y+1][x] && dark == c.modules[y+1][x+1] {
+				result += penaltyBlock
+			}
+		}
+	}
+
+	dark := 0
+	for _, row := range c.modules {
+		for _, module := range row {
+			if module {
+				dark++
+			}
+		}
+	}
+	total := c.size * c.size
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	result += k * penaltyBalance
+
+	return result
+}
+
+// linePenalty scores runs of five or more equal modules and finder-like
+// patterns in one row or column.
+func linePenalty(line []bool) int {
+	result := 0
+
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			result += penaltyRun + run - 5
+		}
+		run = 1
+	}
+
+	for i := 0; i+len(finderLike[0]) <= len(line); i++ {
+		for _, pattern := range finderLike {
+			matches := true
+			for j, dark := range pattern {
+				if line[i+j] != dark {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				result += penaltyFinder
+			}
+		}
+	}
+
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
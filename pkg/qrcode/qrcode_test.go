@@ -0,0 +1,223 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The expected symbols below decode back to their input with an independent
+// QR decoder.
+var knownSymbols = []struct {
+	data    string
+	level   Level
+	version int
+	rows    []string
+}{
+	{
+		data: "hello, world", level: Medium, version: 1,
+		rows: []string{
+			"#######..#.##.#######",
+			"#.....#.##..#.#.....#",
+			"#.###.#..#..#.#.###.#",
+			"#.###.#...##..#.###.#",
+			"#.###.#.#..##.#.###.#",
+			"#.....#....#..#.....#",
+			"#######.#.#.#.#######",
+			"..........#..........",
+			"#.#.#.#..#..#...#..#.",
+			"#.##...###.#....#..##",
+			".#..####.###.#.######",
+			"####.#.######..#...#.",
+			".######.#.##....#....",
+			"........##.#..###.###",
+			"#######..#..##..#.###",
+			"#.....#....#...#...#.",
+			"#.###.#.##.###.#...#.",
+			"#.###.#..#.###.##.##.",
+			"#.###.#.#..##...#.#.#",
+			"#.....#..#.#....#..#.",
+			"#######.####...#...##",
+		},
+	},
+	{
+		data: "https://sho.rt/abc", level: Quartile, version: 2,
+		rows: []string{
+			"#######...#.#####.#######",
+			"#.....#..#....#...#.....#",
+			"#.###.#.#.....#.#.#.###.#",
+			"#.###.#..###...##.#.###.#",
+			"#.###.#.#.#...###.#.###.#",
+			"#.....#.#.#.....#.#.....#",
+			"#######.#.#.#.#.#.#######",
+			".........##..#.##........",
+			".#..#.#.##...#...#.##.#..",
+			"..#.....##...####...##.#.",
+			"..##..###.#.#..#.######..",
+			"...###..####..#..#....##.",
+			"....#.#..#.#.###.##..####",
+			"###.#....##....#....#..#.",
+			"....#.####.##..##.#####..",
+			"..##...#.###..##..###.##.",
+			"##.####.....#.#.#######..",
+			"........#.#######...#....",
+			"#######..###.##.#.#.#....",
+			"#.....#..##.#.#.#...#####",
+			"#.###.#.#..#..#.#######.#",
+			"#.###.#..##..#..#.##..###",
+			"#.###.#...#...#####..#.#.",
+			"#.....#.##....#...######.",
+			"#######...##.###......###",
+		},
+	},
+}
+
+func symbolRows(code *Code) []string {
+	rows := make([]string, code.Size())
+	for y := range rows {
+		var row strings.Builder
+		for x := 0; x < code.Size(); x++ {
+			if code.Dark(x, y) {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		rows[y] = row.String()
+	}
+	return rows
+}
+
+func TestEncode_KnownSymbols(t *testing.T) {
+	for _, known := range knownSymbols {
+		code, err := Encode([]byte(known.data), known.level)
+		require.NoError(t, err)
+		assert.Equal(t, known.version, code.Version(), known.data)
+		assert.Equal(t, known.rows, symbolRows(code), known.data)
+	}
+}
+
+// The byte mode capacities of ISO/IEC 18004 table 7.
+func TestEncode_Capacity(t *testing.T) {
+	capacities := []struct {
+		level    Level
+		version  int
+		capacity int
+	}{
+		{Low, 1, 17}, {Medium, 1, 14}, {Quartile, 1, 11}, {High, 1, 7},
+		{Low, 10, 271}, {High, 10, 119},
+		{Low, 40, 2953}, {High, 40, 1273},
+	}
+	for _, c := range capacities {
+		code, err := Encode(bytes.Repeat([]byte("a"), c.capacity), c.level)
+		require.NoError(t, err)
+		assert.Equal(t, c.version, code.Version(), "%d bytes at level %d", c.capacity, c.level)
+
+		code, err = Encode(bytes.Repeat([]byte("a"), c.capacity+1), c.level)
+		if c.version == maxVersion {
+			assert.ErrorIs(t, err, ErrDataTooLong)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, c.version+1, code.Version(), "%d bytes at level %d", c.capacity+1, c.level)
+	}
+}
+
+// formatWords are the 15 bit format information words of ISO/IEC 18004
+// table C.1, by level and mask.
+var formatWords = map[Level][8]int{
+	Low:      {0x77C4, 0x72F3, 0x7DAA, 0x789D, 0x662F, 0x6318, 0x6C41, 0x6976},
+	Medium:   {0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0},
+	Quartile: {0x355F, 0x3068, 0x3F31, 0x3A06, 0x24B4, 0x2183, 0x2EDA, 0x2BED},
+	High:     {0x1689, 0x13BE, 0x1CE7, 0x19D0, 0x0762, 0x0255, 0x0D0C, 0x083B},
+}
+
+// readFormat returns both copies of the format information of code, the one
+// around the top left finder pattern and the one split between the other two.
+func readFormat(code *Code) (int, int) {
+	var first, second int
+	set := func(word *int, i, x, y int) {
+		if code.Dark(x, y) {
+			*word |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		set(&first, i, 8, i)
+	}
+	set(&first, 6, 8, 7)
+	set(&first, 7, 8, 8)
+	set(&first, 8, 7, 8)
+	for i := 9; i < 15; i++ {
+		set(&first, i, 14-i, 8)
+	}
+	for i := 0; i < 8; i++ {
+		set(&second, i, code.Size()-1-i, 8)
+	}
+	for i := 8; i < 15; i++ {
+		set(&second, i, 8, code.Size()-15+i)
+	}
+	return first, second
+}
+
+func TestEncode_FormatBits(t *testing.T) {
+	for level, words := range formatWords {
+		for _, data := range []string{"a", "hello, world", strings.Repeat("https://sho.rt/", 8)} {
+			code, err := Encode([]byte(data), level)
+			require.NoError(t, err)
+
+			first, second := readFormat(code)
+			assert.Equal(t, first, second, "format copies of %q at level %d", data, level)
+			assert.Contains(t, words[:], first, "format of %q at level %d", data, level)
+			assert.True(t, code.Dark(8, code.Size()-8), "dark module")
+		}
+	}
+}
+
+func TestEncode_VersionBits(t *testing.T) {
+	// The 18 bit version information words of ISO/IEC 18004 table D.1.
+	versions := []struct {
+		length  int
+		version int
+		word    int
+	}{
+		{140, 7, 0x07C94},
+		{180, 8, 0x085BC},
+		{2953, 40, 0x28C69},
+	}
+	for _, v := range versions {
+		code, err := Encode(bytes.Repeat([]byte("a"), v.length), Low)
+		require.NoError(t, err)
+		require.Equal(t, v.version, code.Version())
+
+		var bottomLeft, topRight int
+		for i := 0; i < 18; i++ {
+			a, b := code.Size()-11+i%3, i/3
+			if code.Dark(b, a) {
+				bottomLeft |= 1 << i
+			}
+			if code.Dark(a, b) {
+				topRight |= 1 << i
+			}
+		}
+		assert.Equal(t, v.word, bottomLeft, "version %d", v.version)
+		assert.Equal(t, v.word, topRight, "version %d", v.version)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, level := range map[string]Level{"L": Low, "m": Medium, "Q": Quartile, "h": High} {
+		parsed, err := ParseLevel(name)
+		require.NoError(t, err)
+		assert.Equal(t, level, parsed)
+	}
+	_, err := ParseLevel("X")
+	assert.ErrorIs(t, err, ErrInvalidLevel)
+}
+
+func TestEncode_InvalidLevel(t *testing.T) {
+	_, err := Encode([]byte("a"), Level(4))
+	assert.ErrorIs(t, err, ErrInvalidLevel)
+}
@@ -0,0 +1,90 @@
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// QuietZone is the light border, in modules, that scanners need around a code.
+const QuietZone = 4
+
+var ErrInvalidColor = errors.New("Invalid colour")
+
+// ParseHexColor parses an RGB colour written as rrggbb or #rrggbb.
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+	}
+
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 0xFF}, nil
+}
+
+// moduleScale returns the whole number of pixels per module that fits the
+// code and its quiet zone into size pixels, never less than one.
+func (c *Code) moduleScale(size int) int {
+	return max(1, size/(c.size+2*QuietZone))
+}
+
+// Image draws the code with its quiet zone at most size pixels wide, or
+// wider when size is too small to give every module one pixel.
+func (c *Code) Image(size int, foreground, background color.Color) image.Image {
+	scale := c.moduleScale(size)
+	width := (c.size + 2*QuietZone) * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{background, foreground})
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			if c.Dark(x/scale-QuietZone, y/scale-QuietZone) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the code as a PNG image, see Image.
+func (c *Code) PNG(size int, foreground, background color.Color) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.Image(size, foreground, background)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as an SVG document size pixels wide. The dark modules
+// form a single path, so the image scales without seams.
+func (c *Code) SVG(size int, foreground, background color.RGBA) []byte {
+	width := c.size + 2*QuietZone
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", size, size, width, width)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", hexColor(background))
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, hexColor(foreground))
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.modules[y][x] {
+				fmt.Fprintf(&buf, "M%d,%dh1v1h-1z", x+QuietZone, y+QuietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/>` + "\n</svg>\n")
+
+	return buf.Bytes()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
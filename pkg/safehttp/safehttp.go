@@ -0,0 +1,70 @@
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// MaxRedirects is how many redirects a client made by NewClient follows.
+const MaxRedirects = 5
+
+var ErrPrivateAddress = errors.New("Refusing to connect to a private address")
+
+// NewClient returns an HTTP client for fetching user supplied URLs. Every
+// request, redirects included, is given timeout. Unless allowPrivate is set
+// the client refuses to connect to the addresses IsPrivateIP reports, so a
+// link, or a redirect it leads to, cannot be used to probe the internal
+// network.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", MaxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// refusePrivate runs for every connection after the host name is resolved,
+// so it also catches names that resolve to private addresses.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || IsPrivateIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP.IsPrivate
+// leaves out.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPrivateIP reports whether ip is a loopback, private, shared, unspecified
+// or link-local address, or a multicast address that never leaves the host
+// or link. Links to such addresses are refused, and clients made by
+// NewClient do not connect to them.
+func IsPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
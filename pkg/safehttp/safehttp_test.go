@@ -0,0 +1,34 @@
+package safehttp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPrivateIP(t *testing.T) {
+	cases := map[string]bool{
+		"127.0.0.1":       true,
+		"10.1.2.3":        true,
+		"192.168.0.1":     true,
+		"169.254.169.254": true,
+		"100.64.0.1":      true,
+		"100.127.255.1":   true,
+		"0.0.0.0":         true,
+		"::1":             true,
+		"fe80::1":         true,
+		"ff01::1":         true,
+		"100.128.0.1":     false,
+		"93.184.216.34":   false,
+		"2606:4700::1":    false,
+	}
+	for address, private := range cases {
+		assert.Equal(t, private, IsPrivateIP(net.ParseIP(address)), address)
+	}
+}
+
+func TestRefusePrivate(t *testing.T) {
+	assert.ErrorIs(t, refusePrivate("tcp", "100.64.0.1:80", nil), ErrPrivateAddress)
+	assert.NoError(t, refusePrivate("tcp", "93.184.216.34:443", nil))
+}
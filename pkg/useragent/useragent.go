@@ -0,0 +1,119 @@
+package useragent
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operating systems reported by Parse.
+const (
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSLinux    = "linux"
+	OSChromeOS = "chromeos"
+	OSOther    = "other"
+)
+
+// Device classes reported by Parse.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+)
+
+// Info is what Parse can tell about a client from its User-Agent header.
+type Info struct {
+	OS     string
+	Device string
+}
+
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "facebookexternalhit", "preview", "curl/", "wget/", "python-requests", "go-http-client"}
+
+// Parse classifies a User-Agent header by operating system and device class.
+// It only looks for the markers common browsers send, which is enough to pick
+// an app store, and is not meant to identify browsers or versions.
+func Parse(header string) Info {
+	ua := strings.ToLower(header)
+
+	for _, marker := range botMarkers {
+		if strings.Contains(ua, marker) {
+			return Info{OS: detectOS(ua), Device: DeviceBot}
+		}
+	}
+
+	os := detectOS(ua)
+	return Info{OS: os, Device: detectDevice(ua, os)}
+}
+
+func detectOS(ua string) string {
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return OSIOS
+	case strings.Contains(ua, "android"):
+		return OSAndroid
+	case strings.Contains(ua, "cros"):
+		return OSChromeOS
+	case strings.Contains(ua, "windows"):
+		return OSWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return OSMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return OSLinux
+	default:
+		return OSOther
+	}
+}
+
+func detectDevice(ua, os string) string {
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"):
+		return DeviceTablet
+	// Android tablets leave "Mobile" out of their User-Agent.
+	case os == OSAndroid && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+// PreferredLanguage returns the lower-cased language tag an Accept-Language
+// header ranks highest, such as "de" or "pt-br", or "" when there is none.
+func PreferredLanguage(header string) string {
+	type ranked struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []ranked
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			tags = append(tags, ranked{tag, quality})
+		}
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	return tags[0].tag
+}
@@ -0,0 +1,154 @@
+package wordlist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List is a set of lower-cased entries read from a text file with one entry
+// per line. Blank lines and lines starting with # are skipped. A List is safe
+// for concurrent use and can be reloaded while it is being read.
+type List struct {
+	path string
+
+	mu      sync.RWMutex
+	entries map[string]struct{}
+	modTime time.Time
+	size    int64
+}
+
+// Load reads the list at path.
+func Load(path string) (*List, error) {
+	l := &List{path: path}
+	if _, err := l.Reload(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// New returns a list of the given entries that is not backed by a file.
+func New(entries ...string) *List {
+	l := &List{entries: make(map[string]struct{}, len(entries))}
+	for _, entry := range entries {
+		if entry = normalize(entry); entry != "" {
+			l.entries[entry] = struct{}{}
+		}
+	}
+	return l
+}
+
+// Has reports whether entry is on the list, ignoring case.
+func (l *List) Has(entry string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.entries[strings.ToLower(entry)]
+	return ok
+}
+
+// Entries returns the entries of the list in no particular order.
+func (l *List) Entries() []string {
+	if l == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	entries := make([]string, 0, len(l.entries))
+	for entry := range l.entries {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Reload reads the file again when it was modified since the last load and
+// reports whether it did. The previous entries are kept when reading fails.
+func (l *List) Reload() (bool, error) {
+	if l.path == "" {
+		return false, nil
+	}
+
+	info, err := os.Stat(l.path)
+	if err != nil {
+		return false, fmt.Errorf("could not stat list %s: %w", l.path, err)
+	}
+
+	l.mu.RLock()
+	unchanged := l.entries != nil && info.ModTime().Equal(l.modTime) && info.Size() == l.size
+	l.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	entries, err := read(l.path)
+	if err != nil {
+		return false, err
+	}
+
+	l.mu.Lock()
+	l.entries = entries
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+	l.mu.Unlock()
+	return true, nil
+}
+
+// Watch reloads the list every interval until ctx is done and calls onChange
+// after each reload that changed it. Reload errors are passed to onError, if
+// set, and the previous entries stay in use.
+func (l *List) Watch(ctx context.Context, interval time.Duration, onChange func(), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := l.Reload()
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			if changed && onChange != nil {
+				onChange()
+			}
+		}
+	}
+}
+
+func read(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open list %s: %w", path, err)
+	}
+	defer file.Close()
+
+	entries := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if entry := normalize(scanner.Text()); entry != "" {
+			entries[entry] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read list %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func normalize(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	return strings.ToLower(line)
+}